@@ -1,6 +1,11 @@
 package main
 
-import "github.com/kek-flip/scotch-api/internal/apiserver"
+import (
+	// Timezones of users must resolve even where the system has no tzdata.
+	_ "time/tzdata"
+
+	"github.com/kek-flip/scotch-api/internal/apiserver"
+)
 
 func main() {
 	if err := apiserver.StartServer(); err != nil {
@@ -0,0 +1,84 @@
+package apiserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// authenticateAdmin lets through the requests bearing ADMIN_TOKEN in the
+// Authorization header. Without a configured token nobody is an admin.
+func (s *server) authenticateAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			s.respondErrorCode(w, r, http.StatusUnauthorized, codeUnauthorized, "Wrong admin token:", errUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlerAdminOutboxDead lists the dead outbox messages, oldest first.
+func (s *server) handlerAdminOutboxDead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerAdminOutboxDead()")
+
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
+		afterID, err := p.AfterID()
+		if err != nil {
+			s.respondError(w, r, "Invalid cursor:", err)
+			return
+		}
+
+		limit := p.PageLimit()
+		messages, err := s.store.Outbox().FindByStatus(r.Context(), model.OutboxDead, afterID, limit+1)
+		if err != nil {
+			s.respondError(w, r, "Cannot find outbox messages:", err)
+			return
+		}
+
+		page := &store.OutboxPage{Messages: messages}
+		if len(messages) > limit {
+			page.Messages = messages[:limit]
+			page.NextCursor = store.EncodeCursor(store.IDCursor{ID: messages[limit-1].ID})
+		}
+
+		s.respond(w, http.StatusOK, page)
+	}
+}
+
+// handlerAdminOutboxReplay hands a dead outbox message to the dispatcher
+// again, with all of its attempts.
+func (s *server) handlerAdminOutboxReplay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerAdminOutboxReplay()")
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		m, err := s.store.Outbox().Replay(r.Context(), id, time.Now())
+		if err != nil {
+			s.respondError(w, r, "Cannot replay outbox message:", err)
+			return
+		}
+
+		s.respond(w, http.StatusOK, m)
+	}
+}
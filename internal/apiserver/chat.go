@@ -0,0 +1,188 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// messageRequest is the body of sending and editing a message.
+type messageRequest struct {
+	Body string `json:"body"`
+}
+
+// authorizeThread lets through only the users of the match from the path and
+// puts its thread into the context. To anyone else, and to users who have
+// blocked each other, the thread does not exist.
+func (s *server) authorizeThread(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		matchID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		t, err := s.store.Message().FindThread(r.Context(), matchID)
+		if err != nil {
+			s.respondError(w, r, "Cannot find thread:", err)
+			return
+		}
+
+		if !t.Has(userID) {
+			s.respondError(w, r, "Not a user of the thread:", store.ErrNotFound)
+			return
+		}
+
+		if !s.checkNotBlocked(w, r, t.Other(userID)) {
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxThreadKey, t)))
+	})
+}
+
+// openThread returns the thread of the request, or answers with 409 and
+// returns nil if the thread is locked.
+func (s *server) openThread(w http.ResponseWriter, r *http.Request) *model.Thread {
+	t := r.Context().Value(ctxThreadKey).(*model.Thread)
+	if t.Locked {
+		s.respondError(w, r, "Thread is locked:", store.ErrThreadLocked)
+		return nil
+	}
+
+	return t
+}
+
+// handlerMessages lists the messages of the thread from the newest.
+func (s *server) handlerMessages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerMessages()")
+
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
+		t := r.Context().Value(ctxThreadKey).(*model.Thread)
+
+		messages, err := s.store.Message().FindPageByMatch(r.Context(), t.MatchID, p)
+		if err != nil {
+			s.respondError(w, r, "Cannot find messages:", err)
+			return
+		}
+
+		s.respond(w, http.StatusOK, messages)
+	}
+}
+
+func (s *server) handlerMessageCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerMessageCreate()")
+
+		t := s.openThread(w, r)
+		if t == nil {
+			return
+		}
+
+		req := &messageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid message data format:", err)
+			return
+		}
+
+		m := &model.Message{
+			MatchID: t.MatchID,
+			Sender:  r.Context().Value(ctxUserKey).(*model.User).ID,
+			Body:    req.Body,
+		}
+
+		if err := s.store.Message().Create(r.Context(), m); err != nil {
+			s.respondError(w, r, "Cannot create message:", err)
+			return
+		}
+
+		s.publish(r.Context(), &event{Type: eventMessageCreated, Data: m}, t.User1, t.User2)
+
+		s.respond(w, http.StatusCreated, m)
+	}
+}
+
+// handlerMessageUpdate edits a message the current user has sent.
+func (s *server) handlerMessageUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerMessageUpdate()")
+
+		t := s.openThread(w, r)
+		if t == nil {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["message_id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		req := &messageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid message data format:", err)
+			return
+		}
+
+		m := &model.Message{
+			ID:      id,
+			MatchID: t.MatchID,
+			Sender:  r.Context().Value(ctxUserKey).(*model.User).ID,
+			Body:    req.Body,
+		}
+
+		if err := s.store.Message().Update(r.Context(), m); err != nil {
+			s.respondError(w, r, "Cannot update message:", err)
+			return
+		}
+
+		s.publish(r.Context(), &event{Type: eventMessageUpdated, Data: m}, t.User1, t.User2)
+
+		s.respond(w, http.StatusOK, m)
+	}
+}
+
+// handlerMessageDelete deletes a message the current user has sent. The
+// message stays in the thread without its body.
+func (s *server) handlerMessageDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerMessageDelete()")
+
+		t := s.openThread(w, r)
+		if t == nil {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["message_id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		m := &model.Message{
+			ID:      id,
+			MatchID: t.MatchID,
+			Sender:  r.Context().Value(ctxUserKey).(*model.User).ID,
+		}
+
+		if err := s.store.Message().Delete(r.Context(), m); err != nil {
+			s.respondError(w, r, "Cannot delete message:", err)
+			return
+		}
+
+		s.publish(r.Context(), &event{Type: eventMessageDeleted, Data: m}, t.User1, t.User2)
+	}
+}
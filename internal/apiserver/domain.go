@@ -0,0 +1,23 @@
+package apiserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+// subscribe registers the subscribers to the domain events on the
+// dispatcher. It is called before the dispatcher starts.
+func (s *server) subscribe() {
+	for _, eventType := range model.DomainEventTypes {
+		s.dispatcher.Subscribe(eventType, s.logDomainEvent)
+	}
+}
+
+// logDomainEvent writes the domain event to the log, which analytics is
+// collected from. The payload is left out, since it holds personal data.
+func (s *server) logDomainEvent(ctx context.Context, m *model.OutboxMessage) error {
+	s.logger.Printf("Domain event %s #%d at %s\n", m.Type, m.ID, m.CreatedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,129 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// Error codes are part of the API: clients switch on them, so they must not
+// change once released.
+const (
+	codeInternal         = "internal_error"
+	codeUnauthorized     = "unauthorized"
+	codeWrongCredentials = "wrong_credentials"
+	codeValidationFailed = "validation_failed"
+	codeNotFound         = "not_found"
+	codeDuplicateLogin   = "duplicate_login"
+	codeDuplicatePhone   = "duplicate_phone"
+	codeSelfLike         = "self_like"
+	codeAlreadyLiked     = "already_liked"
+	codeSelfMatch        = "self_match"
+	codeAlreadyMatched   = "already_matched"
+	codeSelfPass         = "self_pass"
+	codeAlreadyPassed    = "already_passed"
+	codeSelfBlock        = "self_block"
+	codeAlreadyBlocked   = "already_blocked"
+	codeSelfReport       = "self_report"
+	codeInvalidData      = "invalid_data"
+	codeStillReferenced  = "still_referenced"
+	codeInvalidPhoto     = "invalid_photo"
+	codeInvalidCursor    = "invalid_cursor"
+	codeNoLocation       = "no_location"
+	codeSuperLikeQuota   = "super_like_quota"
+	codeLimitExceeded    = "limit_exceeded"
+	codeRewindExpired    = "rewind_expired"
+	codeThreadLocked     = "thread_locked"
+	codeBadRequest       = "bad_request"
+	codeInvalidJSON      = "invalid_json"
+	codeInvalidID        = "invalid_id"
+	codeWrongContentType = "wrong_content_type"
+	codeMissingPart      = "missing_part"
+)
+
+var errInternal = errors.New("internal server error")
+
+// encd_err is the body of every error response.
+type encd_err struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+var storeErrors = []errorMapping{
+	{store.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{store.ErrDuplicateLogin, http.StatusConflict, codeDuplicateLogin},
+	{store.ErrDuplicatePhone, http.StatusConflict, codeDuplicatePhone},
+	{store.ErrSelfLike, http.StatusUnprocessableEntity, codeSelfLike},
+	{store.ErrAlreadyLiked, http.StatusConflict, codeAlreadyLiked},
+	{store.ErrSelfMatch, http.StatusUnprocessableEntity, codeSelfMatch},
+	{store.ErrAlreadyMatched, http.StatusConflict, codeAlreadyMatched},
+	{store.ErrSelfPass, http.StatusUnprocessableEntity, codeSelfPass},
+	{store.ErrAlreadyPassed, http.StatusConflict, codeAlreadyPassed},
+	{store.ErrSelfBlock, http.StatusUnprocessableEntity, codeSelfBlock},
+	{store.ErrAlreadyBlocked, http.StatusConflict, codeAlreadyBlocked},
+	{store.ErrSelfReport, http.StatusUnprocessableEntity, codeSelfReport},
+	{store.ErrInvalidData, http.StatusUnprocessableEntity, codeInvalidData},
+	{store.ErrStillReferenced, http.StatusConflict, codeStillReferenced},
+	{store.ErrInvalidPhoto, http.StatusUnprocessableEntity, codeInvalidPhoto},
+	{store.ErrInvalidCursor, http.StatusBadRequest, codeInvalidCursor},
+	{store.ErrNoLocation, http.StatusUnprocessableEntity, codeNoLocation},
+	{store.ErrSuperLikeQuota, http.StatusTooManyRequests, codeSuperLikeQuota},
+	{store.ErrLimitExceeded, http.StatusTooManyRequests, codeLimitExceeded},
+	{store.ErrThreadLocked, http.StatusConflict, codeThreadLocked},
+}
+
+// respondError logs err and answers with the status and code it maps to.
+// Errors that are not known to the API are reported as internal ones
+// without their text, so database details never reach the client.
+func (s *server) respondError(w http.ResponseWriter, r *http.Request, logMsg string, err error) {
+	for _, m := range storeErrors {
+		if errors.Is(err, m.err) {
+			s.respondErrorCode(w, r, m.status, m.code, logMsg, m.err)
+			return
+		}
+	}
+
+	if _, ok := err.(validation.Errors); ok {
+		s.respondErrorCode(w, r, http.StatusUnprocessableEntity, codeValidationFailed, logMsg, err)
+		return
+	}
+
+	s.err_logger.Println(requestID(r), logMsg, err.Error())
+	s.respond(w, http.StatusInternalServerError, encd_err{
+		Code:      codeInternal,
+		Message:   errInternal.Error(),
+		RequestID: requestID(r),
+	})
+}
+
+// respondErrorCode logs err and answers with it as is. Validation errors
+// additionally get their per-field messages in Fields.
+func (s *server) respondErrorCode(w http.ResponseWriter, r *http.Request, status int, code, logMsg string, err error) {
+	s.err_logger.Println(requestID(r), logMsg, err.Error())
+
+	e := encd_err{
+		Code:      code,
+		Message:   err.Error(),
+		RequestID: requestID(r),
+	}
+
+	if errs, ok := err.(validation.Errors); ok {
+		e.Message = "some fields are invalid"
+		e.Fields = make(map[string]string, len(errs))
+		for field, fieldErr := range errs {
+			e.Fields[field] = fieldErr.Error()
+		}
+	}
+
+	s.respond(w, status, e)
+}
@@ -0,0 +1,187 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/outbox"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// eventPageSize is how many logged events are replayed per query.
+const eventPageSize = 100
+
+var (
+	errStreamUnsupported = errors.New("streaming is not supported")
+	errShuttingDown      = errors.New("server is shutting down")
+)
+
+// pendingEvents are the events logged by a transaction. They are pushed to
+// the clients once the transaction is committed.
+type pendingEvents []*model.Event
+
+// record logs an event of eventType for userID in tx.
+func (p *pendingEvents) record(ctx context.Context, tx store.Store, userID int, eventType string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	e := &model.Event{UserID: userID, Type: eventType, Data: b}
+	if err := tx.Event().Create(ctx, e); err != nil {
+		return err
+	}
+
+	*p = append(*p, e)
+
+	return nil
+}
+
+// recordMatch logs an event of eventType for both users of m, each one as
+// User1 of its copy.
+func (p *pendingEvents) recordMatch(ctx context.Context, tx store.Store, eventType string, m *model.Match) error {
+	for _, pair := range [][2]int{{m.User1, m.User2}, {m.User2, m.User1}} {
+		match := *m
+		match.User1, match.User2 = pair[0], pair[1]
+
+		if err := p.record(ctx, tx, pair[0], eventType, &match); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// push sends the events to the connected clients of their users.
+func (s *server) push(ctx context.Context, events pendingEvents) {
+	for _, e := range events {
+		s.publish(ctx, &event{ID: e.ID, Type: e.Type, Data: e.Data}, e.UserID)
+	}
+}
+
+// dissolveMatch deletes the match of the pair in tx, logs match.removed for
+// both users and writes MatchRemoved to the outbox. It returns nil if the
+// pair has no match.
+func dissolveMatch(ctx context.Context, tx store.Store, events *pendingEvents, user1, user2 int) (*model.Match, error) {
+	m, err := tx.Match().FindByUsers(ctx, user1, user2)
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Match().DeleteByUsers(ctx, user1, user2); err != nil {
+		return nil, err
+	}
+
+	if err := outbox.Record(ctx, tx, model.DomainMatchRemoved, m); err != nil {
+		return nil, err
+	}
+
+	return m, events.recordMatch(ctx, tx, eventMatchRemoved, m)
+}
+
+// handlerEvents streams the logged events of the current user as
+// Server-Sent Events. A client that reconnects with Last-Event-ID first gets
+// the events it missed.
+func (s *server) handlerEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerEvents()")
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			s.respondErrorCode(w, r, http.StatusInternalServerError, codeInternal, "Cannot stream events:", errStreamUnsupported)
+			return
+		}
+
+		lastID := 0
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Invalid Last-Event-ID:", err)
+				return
+			}
+			lastID = id
+		}
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		// The client is registered before the replay, so that no event
+		// falls between the two. A push only wakes the stream up: the
+		// events are always read from the log, since pushes of concurrent
+		// transactions may come in any order.
+		c := newClient(nil, userID)
+		if !s.hub.register(c) {
+			s.respondErrorCode(w, r, http.StatusServiceUnavailable, codeInternal, "Cannot stream events:", errShuttingDown)
+			return
+		}
+		defer s.hub.unregister(c)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		replay := func() bool {
+			for {
+				events, err := s.store.Event().FindAfter(r.Context(), userID, lastID, eventPageSize)
+				if err != nil {
+					s.err_logger.Println(requestID(r), "Cannot find events:", err.Error())
+					return false
+				}
+
+				for _, e := range events {
+					writeEvent(w, e.ID, e.Type, e.Data)
+					lastID = e.ID
+				}
+				flusher.Flush()
+
+				if len(events) < eventPageSize {
+					return true
+				}
+			}
+		}
+
+		if !replay() {
+			return
+		}
+
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case e := <-c.send:
+				// Events without an ID are not logged.
+				if e.ID == 0 {
+					continue
+				}
+
+				if !replay() {
+					return
+				}
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case <-c.done:
+				return
+			case <-r.Context().Done():
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes one Server-Sent Event. data must be single-line JSON.
+func writeEvent(w http.ResponseWriter, id int, eventType string, data []byte) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, eventType, data)
+}
@@ -0,0 +1,158 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/pubsub"
+)
+
+// Types of the events pushed to realtime clients.
+const (
+	eventMessageCreated = "message.created"
+	eventMessageUpdated = "message.updated"
+	eventMessageDeleted = "message.deleted"
+	eventMessageRead    = "message.read"
+	eventTyping         = "typing"
+	eventLikeReceived   = model.EventLikeReceived
+	eventMatchCreated   = model.EventMatchCreated
+	eventMatchRemoved   = model.EventMatchRemoved
+	eventError          = "error"
+)
+
+// event is what realtime clients receive. Only the events from the event log
+// have an ID.
+type event struct {
+	ID   int         `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// delivery is an event on its way to the connections of Users, on whichever
+// instance they are.
+type delivery struct {
+	Users []int           `json:"users"`
+	ID    int             `json:"id,omitempty"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// usePubSub makes the server deliver events through ps.
+func (s *server) usePubSub(ps pubsub.PubSub) {
+	s.pubsub = ps
+	ps.Subscribe(s.receive)
+}
+
+// publish sends e to the connections of the users on every instance.
+// Realtime delivery is best effort, so failures are only logged.
+func (s *server) publish(ctx context.Context, e *event, userIDs ...int) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		s.err_logger.Println("Cannot encode event:", err.Error())
+		return
+	}
+
+	b, err := json.Marshal(&delivery{Users: userIDs, ID: e.ID, Type: e.Type, Data: data})
+	if err != nil {
+		s.err_logger.Println("Cannot encode event:", err.Error())
+		return
+	}
+
+	if err := s.pubsub.Publish(ctx, b); err != nil {
+		s.err_logger.Println("Cannot publish event:", err.Error())
+	}
+}
+
+// receive hands a published delivery to the connections of this instance.
+func (s *server) receive(payload []byte) {
+	d := &delivery{}
+	if err := json.Unmarshal(payload, d); err != nil {
+		s.err_logger.Println("Cannot decode event:", err.Error())
+		return
+	}
+
+	s.hub.publish(&event{ID: d.ID, Type: d.Type, Data: d.Data}, d.Users...)
+}
+
+// hub keeps the realtime connections of every user, who may be connected
+// from several devices at once.
+type hub struct {
+	mu      sync.Mutex
+	clients map[int]map[*client]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newHub() *hub {
+	return &hub{
+		clients: make(map[int]map[*client]struct{}),
+	}
+}
+
+// register adds c to the hub. It returns false once the hub is closed.
+func (h *hub) register(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	if h.clients[c.userID] == nil {
+		h.clients[c.userID] = make(map[*client]struct{})
+	}
+	h.clients[c.userID][c] = struct{}{}
+	h.wg.Add(1)
+
+	return true
+}
+
+func (h *hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c.userID][c]; !ok {
+		return
+	}
+
+	delete(h.clients[c.userID], c)
+	if len(h.clients[c.userID]) == 0 {
+		delete(h.clients, c.userID)
+	}
+	h.wg.Done()
+}
+
+// publish queues e for every connection of the users. A connection whose
+// send buffer is full is dropped instead of holding up the others; its
+// client reconnects and refetches what it missed.
+func (h *hub) publish(e *event, userIDs ...int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range userIDs {
+		for c := range h.clients[id] {
+			select {
+			case c.send <- e:
+			default:
+				c.stop(websocket.CloseTryAgainLater, "client is too slow")
+			}
+		}
+	}
+}
+
+// close drops every connection and waits until they are closed.
+func (h *hub) close() {
+	h.mu.Lock()
+	h.closed = true
+	for _, clients := range h.clients {
+		for c := range clients {
+			c.stop(websocket.CloseGoingAway, "server is shutting down")
+		}
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+}
@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+const (
+	limitLikesDaily = "likes_daily"
+	limitLikesBurst = "likes_burst"
+)
+
+// defaultLikeLimits keep scripts from liking everyone while staying out of
+// the way of people swiping by hand.
+var defaultLikeLimits = []*model.Limit{
+	{Name: limitLikesDaily, Max: 300},
+	{Name: limitLikesBurst, Max: 30, Period: time.Minute},
+}
+
+// getLikeLimits reads LIKES_PER_DAY, a number, and LIKE_BURST, a number per
+// duration such as "30/1m". Zero turns a limit off.
+func getLikeLimits() ([]*model.Limit, error) {
+	daily, burst := *defaultLikeLimits[0], *defaultLikeLimits[1]
+
+	if v := os.Getenv("LIKES_PER_DAY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		daily.Max = n
+	}
+
+	if v := os.Getenv("LIKE_BURST"); v != "" {
+		max, period, ok := strings.Cut(v, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid LIKE_BURST %q: expected <count>/<duration>", v)
+		}
+
+		n, err := strconv.Atoi(max)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := time.ParseDuration(period)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid LIKE_BURST %q: duration must be positive", v)
+		}
+
+		burst.Max, burst.Period = n, d
+	}
+
+	limits := make([]*model.Limit, 0, 2)
+	for _, l := range []model.Limit{daily, burst} {
+		if l.Max > 0 {
+			l := l
+			limits = append(limits, &l)
+		}
+	}
+
+	return limits, nil
+}
+
+// limitError is store.ErrLimitExceeded with the end of the window that ran
+// out.
+type limitError struct {
+	retryAt time.Time
+}
+
+func (e *limitError) Error() string {
+	return store.ErrLimitExceeded.Error()
+}
+
+func (e *limitError) Unwrap() error {
+	return store.ErrLimitExceeded
+}
+
+// countLike counts a like of u against every like limit in tx, so that the
+// counters go back if the like is not created. Once a limit is reached it
+// returns a *limitError.
+func (s *server) countLike(ctx context.Context, tx store.Store, u *model.User, now time.Time) error {
+	for _, l := range s.likeLimits {
+		prev, err := tx.Counter().FindLast(ctx, u.ID, l.Name)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+
+		c := l.Counter(u, prev, now)
+		err = tx.Counter().Increment(ctx, c, l.Max)
+		if err == store.ErrLimitExceeded {
+			return &limitError{retryAt: c.WindowEnd}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// respondLikeError answers a failed like, with Retry-After if a like limit
+// was reached.
+func (s *server) respondLikeError(w http.ResponseWriter, r *http.Request, now time.Time, err error) {
+	var le *limitError
+	if errors.As(err, &le) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter(now, le.retryAt)))
+	}
+
+	s.respondError(w, r, "Cannot create like:", err)
+}
+
+// retryAfter returns the whole seconds from now until t, at least one.
+func retryAfter(now, t time.Time) int {
+	return int(math.Max(1, math.Ceil(t.Sub(now).Seconds())))
+}
@@ -1,6 +1,13 @@
 package apiserver
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+var errHijackUnsupported = errors.New("connection cannot be hijacked")
 
 type responceWriter struct {
 	http.ResponseWriter
@@ -11,3 +18,21 @@ func (rw *responceWriter) WriteHeader(statusCode int) {
 	rw.code = statusCode
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Flush lets event streams reach the client while the request goes on.
+func (rw *responceWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websockets take over the connection of a logged request.
+func (rw *responceWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackUnsupported
+	}
+
+	rw.code = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
@@ -6,63 +6,151 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kek-flip/scotch-api/internal/migrator"
 	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/outbox"
+	"github.com/kek-flip/scotch-api/internal/pubsub"
+	"github.com/kek-flip/scotch-api/internal/ranking"
 	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/filestore"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/kek-flip/scotch-api/migrations"
 )
 
-type encd_err struct {
-	Error string `json:"error"`
-}
-
 type ctxKey int
 
+const (
+	defaultQueryTimeout = 5 * time.Second
+	defaultRewindWindow = 5 * time.Minute
+	// shutdownTimeout bounds waiting for requests in flight on shutdown.
+	shutdownTimeout = 10 * time.Second
+	// pubsubChannel is where the instances exchange realtime events.
+	pubsubChannel = "scotch_events"
+	// lastSeenPrecision limits how often a user's activity is written.
+	lastSeenPrecision = 5 * time.Minute
+)
+
 const (
 	sessionName        = "scotch"
 	ctxUserKey  ctxKey = iota
 	ctxLikeKey  ctxKey = iota
+	ctxRequestIDKey
+	ctxThreadKey
 )
 
 var (
 	errWrongLoginOrPassword = errors.New("wrong login or password")
 	errUnauthorized         = errors.New("you are not authenticated")
-	errNoSuchUser           = errors.New("no user with this id")
 	errWrongContentType     = errors.New("expected multipart")
 	errEmptyUser            = errors.New("expected user data")
 	errEmptyPhoto           = errors.New("expected photo")
+	errSchemaBehind         = errors.New("database schema is behind")
+	errRewindExpired        = errors.New("last swipe is too old to rewind")
+	errUnknownPubSub        = errors.New("unknown pubsub")
 )
 
+// swipeResult is the like created by a swipe together with the match it
+// produced, if any.
+type swipeResult struct {
+	*model.Like
+	Matched bool         `json:"matched"`
+	Match   *model.Match `json:"match,omitempty"`
+}
+
+// rewindResult is the swipe undone by a rewind and the match dissolved with
+// it, if any.
+type rewindResult struct {
+	Like  *model.Like  `json:"like,omitempty"`
+	Pass  *model.Pass  `json:"pass,omitempty"`
+	Match *model.Match `json:"match,omitempty"`
+}
+
 type server struct {
 	router       *mux.Router
-	store        *store.Store
-	photoStore   *store.PhotoStore
+	store        store.Store
+	photoStore   store.PhotoStore
 	sessionStore *sessions.CookieStore
+	ranker       *ranking.Ranker
+	hub          *hub
+	pubsub       pubsub.PubSub
+	dispatcher   *outbox.Dispatcher
+	likeLimits   []*model.Limit
+	rewindWindow time.Duration
+	feedWindow   int
+	adminToken   string
+	debug        bool
 	err_logger   *log.Logger
 	logger       *log.Logger
 }
 
+// feedPage is a page of the feed. Scores are only filled in debug mode.
+type feedPage struct {
+	*store.UserPage
+	Scores []*ranking.Score `json:"scores,omitempty"`
+}
+
+// defaultFeedWindow is how many candidates the feed ranks, taken from the
+// ones seen most recently. Pages are cut from the ranked window; the
+// candidates beyond it come into it as they come back or the ones in it are
+// swiped.
+const defaultFeedWindow = 500
+
+// feedCursor is the position of the last candidate of a ranked feed page.
+// Now pins the time the scores are computed at, so that they do not change
+// from page to page.
+type feedCursor struct {
+	Score float64   `json:"s"`
+	ID    int       `json:"id"`
+	Now   time.Time `json:"t"`
+}
+
 func StartServer() error {
-	conn, err := pgx.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+	poolConfig, err := getPoolConfig()
+	if err != nil {
+		return err
+	}
+
+	pool, err := sqlstore.NewPool(context.Background(), os.Getenv("DATABASE_URL"), poolConfig)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	if err := checkSchema(context.Background(), pool); err != nil {
+		return err
+	}
+
+	queryTimeout, err := getQueryTimeout()
 	if err != nil {
 		return err
 	}
-	defer conn.Close(context.Background())
 
-	st := store.NewStore(conn)
+	st := sqlstore.New(pool, queryTimeout)
 
-	photoStore, err := store.NewPhotoStore(os.Getenv("PHOTOSTORE_PATH"))
+	superLikesPerDay, err := getSuperLikesPerDay()
+	if err != nil {
+		return err
+	}
+	st.SetSuperLikesPerDay(superLikesPerDay)
+
+	photoStore, err := filestore.New(os.Getenv("PHOTOSTORE_PATH"))
 	if err != nil {
 		return err
 	}
@@ -73,9 +161,75 @@ func StartServer() error {
 	}
 	sessionStore := sessions.NewCookieStore(key)
 
+	weights, err := ranking.ParseWeights(os.Getenv("RANKING_WEIGHTS"))
+	if err != nil {
+		return err
+	}
+
+	ranker, err := ranking.New(weights)
+	if err != nil {
+		return err
+	}
+
+	likeLimits, err := getLikeLimits()
+	if err != nil {
+		return err
+	}
+
+	rewindWindow, err := getRewindWindow()
+	if err != nil {
+		return err
+	}
+
+	debug, err := getDebug()
+	if err != nil {
+		return err
+	}
+
 	server := newServer(st, photoStore, sessionStore)
+	server.ranker = ranker
+	server.likeLimits = likeLimits
+	server.rewindWindow = rewindWindow
+	server.adminToken = os.Getenv("ADMIN_TOKEN")
+	server.debug = debug
+
+	ps, err := getPubSub(pool, server.err_logger)
+	if err != nil {
+		return err
+	}
+	server.usePubSub(ps)
+	defer ps.Close()
+
+	server.dispatcher.Start()
+	defer server.dispatcher.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":80", Handler: server}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
 	server.logger.Print("Server is listening on :80 ...\n\n")
-	return http.ListenAndServe(":80", server)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.logger.Print("Server is shutting down ...\n\n")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Realtime connections never go idle on their own, so they are closed
+	// first for Shutdown to wait only for the ordinary requests.
+	server.hub.close()
+
+	return srv.Shutdown(shutdownCtx)
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -103,16 +257,137 @@ func getMasterKey() ([]byte, error) {
 	return key, nil
 }
 
-func newServer(st *store.Store, ps *store.PhotoStore, ss *sessions.CookieStore) *server {
+// checkSchema refuses to serve a database that misses migrations.
+func checkSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	m, err := migrator.New(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) != 0 {
+		return fmt.Errorf("%w: %d pending migrations, run `migrate up` first", errSchemaBehind, len(pending))
+	}
+
+	return nil
+}
+
+func getPoolConfig() (*sqlstore.PoolConfig, error) {
+	c := sqlstore.NewPoolConfig()
+
+	if v := os.Getenv("DB_MAX_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		c.MaxConns = int32(n)
+	}
+
+	if v := os.Getenv("DB_MIN_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		c.MinConns = int32(n)
+	}
+
+	durations := map[string]*time.Duration{
+		"DB_MAX_CONN_IDLE_TIME":  &c.MaxConnIdleTime,
+		"DB_MAX_CONN_LIFETIME":   &c.MaxConnLifetime,
+		"DB_HEALTH_CHECK_PERIOD": &c.HealthCheckPeriod,
+	}
+	for name, d := range durations {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		*d = parsed
+	}
+
+	return c, nil
+}
+
+func getQueryTimeout() (time.Duration, error) {
+	v := os.Getenv("DB_QUERY_TIMEOUT")
+	if v == "" {
+		return defaultQueryTimeout, nil
+	}
+
+	return time.ParseDuration(v)
+}
+
+// getRewindWindow reads REWIND_WINDOW, how long a swipe can be undone.
+func getRewindWindow() (time.Duration, error) {
+	v := os.Getenv("REWIND_WINDOW")
+	if v == "" {
+		return defaultRewindWindow, nil
+	}
+
+	return time.ParseDuration(v)
+}
+
+// getSuperLikesPerDay reads SUPER_LIKES_PER_DAY, the daily super like quota
+// of every user.
+func getSuperLikesPerDay() (int, error) {
+	v := os.Getenv("SUPER_LIKES_PER_DAY")
+	if v == "" {
+		return store.DefaultSuperLikesPerDay, nil
+	}
+
+	return strconv.Atoi(v)
+}
+
+// getPubSub reads PUBSUB, how events reach the realtime connections: through
+// Postgres, so that every instance gets them, or within the process.
+func getPubSub(pool *pgxpool.Pool, logger *log.Logger) (pubsub.PubSub, error) {
+	switch v := os.Getenv("PUBSUB"); v {
+	case "", "postgres":
+		return pubsub.NewPostgres(pool, pubsubChannel, logger), nil
+	case "memory":
+		return pubsub.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownPubSub, v)
+	}
+}
+
+// getDebug reads DEBUG, which enables the diagnostic parts of responses.
+func getDebug() (bool, error) {
+	v := os.Getenv("DEBUG")
+	if v == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(v)
+}
+
+func newServer(st store.Store, ps store.PhotoStore, ss *sessions.CookieStore) *server {
 	s := &server{
 		router:       mux.NewRouter(),
 		store:        st,
 		photoStore:   ps,
 		sessionStore: ss,
+		ranker:       ranking.Default(),
+		hub:          newHub(),
+		likeLimits:   defaultLikeLimits,
+		rewindWindow: defaultRewindWindow,
+		feedWindow:   defaultFeedWindow,
 		err_logger:   newErrLogger(),
 		logger:       newLogger(),
 	}
 
+	s.dispatcher = outbox.NewDispatcher(st, outbox.NewConfig(), s.err_logger)
+	s.subscribe()
+
+	s.usePubSub(pubsub.NewMemory())
 	s.configRouter()
 
 	return s
@@ -129,6 +404,7 @@ func newErrLogger() *log.Logger {
 }
 
 func (s *server) configRouter() {
+	s.router.Use(s.setRequestID)
 	s.router.Use(s.logRequest)
 	s.router.HandleFunc("/users", s.handlerUserCreate()).Methods("POST")
 	s.router.HandleFunc("/sessions", s.handlerSessionCreate()).Methods("POST")
@@ -140,6 +416,11 @@ func (s *server) configRouter() {
 	userSubrouter.HandleFunc("/current", s.handlerCurrentUser()).Methods("GET")
 	userSubrouter.HandleFunc("/current", s.handlerUserUpdate()).Methods("PATCH", "PUT")
 	userSubrouter.HandleFunc("/current", s.handlerUserDelete()).Methods("DELETE")
+	userSubrouter.HandleFunc("/current/preferences", s.handlerPreferences()).Methods("GET")
+	userSubrouter.HandleFunc("/current/preferences", s.handlerPreferencesUpdate()).Methods("PUT")
+	userSubrouter.HandleFunc("/current/location", s.handlerLocationUpdate()).Methods("PUT")
+	userSubrouter.HandleFunc("/current/location", s.handlerLocationDelete()).Methods("DELETE")
+	userSubrouter.HandleFunc("/current/super_likes", s.handlerSuperLikeQuota()).Methods("GET")
 	userSubrouter.HandleFunc("/liked", s.handlerLikedUsers()).Methods("GET")
 	userSubrouter.HandleFunc("/liked_by", s.handlerLikedByUsers()).Methods("GET")
 	userSubrouter.HandleFunc("/matches", s.handlerUserMathces()).Methods("GET")
@@ -147,6 +428,10 @@ func (s *server) configRouter() {
 	userSubrouter.HandleFunc("/filter", s.handlerUsersByFilter()).Methods("GET")
 	userSubrouter.HandleFunc("/all", s.handlerUsersAll()).Methods("GET")
 
+	feedSubrouter := s.router.PathPrefix("/feed").Subrouter()
+	feedSubrouter.Use(s.authenticateUser)
+	feedSubrouter.HandleFunc("", s.handlerFeed()).Methods("GET")
+
 	photoSubrouter := s.router.PathPrefix("/photos").Subrouter()
 	photoSubrouter.Use(s.authenticateUser)
 	photoSubrouter.HandleFunc("/{id:[0-9]+}", s.handlerPhoto()).Methods("GET")
@@ -159,9 +444,50 @@ func (s *server) configRouter() {
 
 	likeSubrouter := s.router.PathPrefix("/likes").Subrouter()
 	likeSubrouter.Use(s.authenticateUser)
-	likeSubrouter.Use(s.checkMatch)
+	likeSubrouter.Use(s.parseLike)
 	likeSubrouter.HandleFunc("", s.handlerLikeCreate()).Methods("POST")
 	likeSubrouter.HandleFunc("", s.handlerLikeDelete()).Methods("DELETE")
+
+	passSubrouter := s.router.PathPrefix("/passes").Subrouter()
+	passSubrouter.Use(s.authenticateUser)
+	passSubrouter.HandleFunc("", s.handlerPassCreate()).Methods("POST")
+
+	blockSubrouter := s.router.PathPrefix("/blocks").Subrouter()
+	blockSubrouter.Use(s.authenticateUser)
+	blockSubrouter.HandleFunc("", s.handlerBlockCreate()).Methods("POST")
+
+	reportSubrouter := s.router.PathPrefix("/reports").Subrouter()
+	reportSubrouter.Use(s.authenticateUser)
+	reportSubrouter.HandleFunc("", s.handlerReportCreate()).Methods("POST")
+
+	swipeSubrouter := s.router.PathPrefix("/swipes").Subrouter()
+	swipeSubrouter.Use(s.authenticateUser)
+	swipeSubrouter.HandleFunc("/rewind", s.handlerSwipeRewind()).Methods("POST")
+
+	matchSubrouter := s.router.PathPrefix("/matches").Subrouter()
+	matchSubrouter.Use(s.authenticateUser)
+	matchSubrouter.HandleFunc("/{id:[0-9]+}", s.handlerMatchDelete()).Methods("DELETE")
+
+	threadSubrouter := matchSubrouter.PathPrefix("/{id:[0-9]+}/messages").Subrouter()
+	threadSubrouter.Use(s.authorizeThread)
+	threadSubrouter.HandleFunc("", s.handlerMessages()).Methods("GET")
+	threadSubrouter.HandleFunc("", s.handlerMessageCreate()).Methods("POST")
+	threadSubrouter.HandleFunc("/{message_id:[0-9]+}", s.handlerMessageUpdate()).Methods("PATCH", "PUT")
+	threadSubrouter.HandleFunc("/{message_id:[0-9]+}", s.handlerMessageDelete()).Methods("DELETE")
+
+	eventSubrouter := s.router.PathPrefix("/events").Subrouter()
+	eventSubrouter.Use(s.authenticateUser)
+	eventSubrouter.HandleFunc("", s.handlerEvents()).Methods("GET")
+
+	wsSubrouter := s.router.PathPrefix("/ws").Subrouter()
+	wsSubrouter.Use(s.authenticateUser)
+	wsSubrouter.HandleFunc("", s.handlerWS()).Methods("GET")
+
+	adminSubrouter := s.router.PathPrefix("/admin").Subrouter()
+	adminSubrouter.Use(s.authenticateAdmin)
+	adminSubrouter.HandleFunc("/users/{id:[0-9]+}/limits", s.handlerAdminLimits()).Methods("GET")
+	adminSubrouter.HandleFunc("/outbox/dead", s.handlerAdminOutboxDead()).Methods("GET")
+	adminSubrouter.HandleFunc("/outbox/{id:[0-9]+}/replay", s.handlerAdminOutboxReplay()).Methods("POST")
 }
 
 func (s *server) respond(w http.ResponseWriter, status int, data interface{}) {
@@ -177,31 +503,96 @@ func (s *server) respondPhoto(w http.ResponseWriter, p []byte) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// parsePagination reads the optional limit and cursor query parameters.
+func (s *server) parsePagination(r *http.Request) (*store.Pagination, error) {
+	p := &store.Pagination{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		p.Limit = limit
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// setDistances fills in how far users are from the current user. Users
+// without a location, or all of them if the current user has none, get no
+// distance.
+func (s *server) setDistances(r *http.Request, users ...*model.User) {
+	origin := r.Context().Value(ctxUserKey).(*model.User).Location
+	if origin == nil {
+		return
+	}
+
+	for _, u := range users {
+		if u.Location != nil {
+			u.Distance = origin.ApproxDistanceTo(u.Location)
+		}
+	}
+}
+
+// checkNotBlocked answers with 404 and returns false if the current user and
+// the user with id have blocked each other, so that to both of them the other
+// one looks deleted.
+func (s *server) checkNotBlocked(w http.ResponseWriter, r *http.Request, id int) bool {
+	userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+	blocked, err := s.store.Block().Exists(r.Context(), userID, id)
+	if err != nil {
+		s.respondError(w, r, "Cannot check blocks:", err)
+		return false
+	}
+
+	if blocked {
+		s.respondError(w, r, "User is blocked:", store.ErrNotFound)
+		return false
+	}
+
+	return true
+}
+
 func (s *server) authenticateUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Authenticating user...")
 
 		session, err := s.sessionStore.Get(r, sessionName)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find session:", err.Error())
+			s.respondError(w, r, "Cannot find session:", err)
 			return
 		}
 
 		id, ok := session.Values["user_id"]
 		if !ok {
-			s.respond(w, http.StatusUnauthorized, encd_err{errUnauthorized.Error()})
-			s.err_logger.Println("Cannot find user_id:", errUnauthorized.Error())
+			s.respondErrorCode(w, r, http.StatusUnauthorized, codeUnauthorized, "Cannot find user_id:", errUnauthorized)
 			return
 		}
 
-		u, err := s.store.User().FindById(id.(int))
+		u, err := s.store.User().FindById(r.Context(), id.(int))
+		if err == store.ErrNotFound {
+			s.respondErrorCode(w, r, http.StatusUnauthorized, codeUnauthorized, "Session user is gone:", errUnauthorized)
+			return
+		}
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot get user data:", err.Error())
+			s.respondError(w, r, "Cannot get user data:", err)
 			return
 		}
 
+		if now := time.Now(); now.Sub(u.LastSeenAt) > lastSeenPrecision {
+			if err := s.store.User().UpdateLastSeen(r.Context(), u.ID, now); err != nil {
+				s.err_logger.Println(requestID(r), "Cannot update last seen:", err.Error())
+			}
+			u.LastSeenAt = now
+		}
+
 		s.logger.Println("Authentication complete")
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxUserKey, u)))
 	})
@@ -209,7 +600,7 @@ func (s *server) authenticateUser(next http.Handler) http.Handler {
 
 func (s *server) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s.logger.Printf("Started %s %s by %s\n", r.Method, r.RequestURI, r.RemoteAddr)
+		s.logger.Printf("Started %s %s by %s [%s]\n", r.Method, r.RequestURI, r.RemoteAddr, requestID(r))
 
 		start := time.Now()
 		rw := &responceWriter{w, http.StatusOK}
@@ -225,37 +616,17 @@ func (s *server) logRequest(next http.Handler) http.Handler {
 	})
 }
 
-func (s *server) checkMatch(next http.Handler) http.Handler {
+func (s *server) parseLike(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		l := &model.Like{}
 
 		if err := json.NewDecoder(r.Body).Decode(l); err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Invalid like data format:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid like data format:", err)
 			return
 		}
 
 		l.UserID = r.Context().Value(ctxUserKey).(*model.User).ID
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxLikeKey, l)))
-
-		if r.Method == http.MethodDelete {
-			return
-		}
-
-		if _, err := s.store.Like().FindMatchLike(l); err != nil {
-			return
-		}
-
-		m := &model.Match{
-			User1: l.UserID,
-			User2: l.LikedUser,
-		}
-
-		if err := s.store.Match().Create(m); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot create match:", err.Error())
-			return
-		}
 	})
 }
 
@@ -266,14 +637,12 @@ func (s *server) handlerUserCreate() http.HandlerFunc {
 		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-type"))
 
 		if err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Cannot get content-type", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeWrongContentType, "Cannot get content-type:", err)
 			return
 		}
 
 		if !strings.HasPrefix(mediaType, "multipart/") {
-			s.respond(w, http.StatusBadRequest, encd_err{errWrongContentType.Error()})
-			s.err_logger.Println("Wrong content-type:", errWrongContentType.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeWrongContentType, "Wrong content-type:", errWrongContentType)
 			return
 		}
 
@@ -287,8 +656,7 @@ func (s *server) handlerUserCreate() http.HandlerFunc {
 				break
 			}
 			if err != nil {
-				s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-				s.err_logger.Println("Cannot parse request part:", err.Error())
+				s.respondError(w, r, "Cannot parse request part:", err)
 				return
 			}
 
@@ -301,37 +669,50 @@ func (s *server) handlerUserCreate() http.HandlerFunc {
 		}
 
 		if up.Len() == 0 {
-			s.respond(w, http.StatusBadRequest, encd_err{errEmptyUser.Error()})
-			s.err_logger.Println("Cannot create user:", errEmptyUser.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeMissingPart, "Cannot create user:", errEmptyUser)
 			return
 		}
 
-		if up.Len() == 0 {
-			s.respond(w, http.StatusBadRequest, encd_err{errEmptyPhoto.Error()})
-			s.err_logger.Println("Cannot create user:", errEmptyPhoto.Error())
+		if pp.Len() == 0 {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeMissingPart, "Cannot create user:", errEmptyPhoto)
 			return
 		}
 
 		u := &model.User{}
 		if err := json.NewDecoder(&up).Decode(u); err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Invalid user data:", err.Error())
-			return
-		}
-		if err := s.store.User().Create(u); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot create user:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid user data:", err)
 			return
 		}
+		// The photo is saved within the transaction, so that a photo that
+		// cannot be saved leaves neither the user nor its registration
+		// behind.
+		saved := false
+		err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := tx.User().Create(r.Context(), u); err != nil {
+				return err
+			}
 
-		if err := s.photoStore.Create(pp.Bytes(), u.ID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot save image:", err.Error())
+			if err := s.photoStore.Create(pp.Bytes(), u.ID); err != nil {
+				return err
+			}
+			saved = true
+
+			u.ClearPassword()
+
+			return outbox.Record(r.Context(), tx, model.DomainUserRegistered, u)
+		})
+
+		if err != nil {
+			if saved {
+				if err := s.photoStore.DeleteByName(strconv.Itoa(u.ID)); err != nil {
+					s.err_logger.Println(requestID(r), "Cannot delete photo:", err.Error())
+				}
+			}
+
+			s.respondError(w, r, "Cannot create user:", err)
 			return
 		}
 
-		u.ClearPassword()
-
 		s.respond(w, http.StatusCreated, u)
 	}
 }
@@ -344,18 +725,21 @@ func (s *server) handlerUser() http.HandlerFunc {
 
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Indalid id:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
 			return
 		}
 
-		u, err := s.store.User().FindById(id)
+		if !s.checkNotBlocked(w, r, id) {
+			return
+		}
+
+		u, err := s.store.User().FindById(r.Context(), id)
 		if err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{errNoSuchUser.Error()})
-			s.err_logger.Println("Cannot find user:", errNoSuchUser.Error())
+			s.respondError(w, r, "Cannot find user:", err)
 			return
 		}
 
+		s.setDistances(r, u)
 		s.respond(w, http.StatusOK, u)
 	}
 }
@@ -366,23 +750,20 @@ func (s *server) handlerUserUpdate() http.HandlerFunc {
 
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		u, err := s.store.User().FindById(userID)
+		u, err := s.store.User().FindById(r.Context(), userID)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{errNoSuchUser.Error()})
-			s.err_logger.Println("Cannot find user:", errNoSuchUser.Error())
+			s.respondError(w, r, "Cannot find user:", err)
 			return
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(u); err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Invalid user data format:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid user data format:", err)
 			return
 		}
 
-		err = s.store.User().Update(u)
+		err = s.store.User().Update(r.Context(), u)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot update user:", err.Error())
+			s.respondError(w, r, "Cannot update user:", err)
 			return
 		}
 
@@ -407,8 +788,7 @@ func (s *server) handlerCurrentUserPhoto() http.HandlerFunc {
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 		photo, err := s.photoStore.FindById(userID)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find current user photo:", err.Error())
+			s.respondError(w, r, "Cannot find current user photo:", err)
 			return
 		}
 
@@ -420,28 +800,33 @@ func (s *server) handlerLikedUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Processing by handlerLikesLiked()")
 
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		likes, err := s.store.Like().FindByUserID(userID)
+		likes, err := s.store.Like().FindPageByUserID(r.Context(), userID, p)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find likes:", err.Error())
+			s.respondError(w, r, "Cannot find likes:", err)
 			return
 		}
 
-		users := make([]*model.User, 0)
-		for _, v := range likes {
-			u, err := s.store.User().FindById(v.LikedUser)
-			if err != nil {
-				s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-				s.err_logger.Println("Cannot find users:", err.Error())
-				return
-			}
+		ids := make([]int, 0, len(likes.Likes))
+		for _, v := range likes.Likes {
+			ids = append(ids, v.LikedUser)
+		}
 
-			users = append(users, u)
+		users, err := s.store.User().FindByIDs(r.Context(), ids)
+		if err != nil {
+			s.respondError(w, r, "Cannot find users:", err)
+			return
 		}
 
-		s.respond(w, http.StatusOK, users)
+		s.setDistances(r, users...)
+		s.respond(w, http.StatusOK, store.UserPage{Users: users, NextCursor: likes.NextCursor})
 	}
 }
 
@@ -449,28 +834,57 @@ func (s *server) handlerLikedByUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Processing by handlerLikedByUsers()")
 
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		likes, err := s.store.Like().FindByLikedUser(userID)
+		likes, err := s.store.Like().FindPageByLikedUser(r.Context(), userID, p)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find likes:", err.Error())
+			s.respondError(w, r, "Cannot find likes:", err)
 			return
 		}
 
-		users := make([]*model.User, 0)
-		for _, v := range likes {
-			u, err := s.store.User().FindById(v.UserID)
-			if err != nil {
-				s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-				s.err_logger.Println("Cannot find users:", err.Error())
-				return
-			}
+		ids := make([]int, 0, len(likes.Likes))
+		super := make(map[int]bool)
+		for _, v := range likes.Likes {
+			ids = append(ids, v.UserID)
+			super[v.UserID] = v.IsSuper()
+		}
+
+		users, err := s.store.User().FindByIDs(r.Context(), ids)
+		if err != nil {
+			s.respondError(w, r, "Cannot find users:", err)
+			return
+		}
+
+		for _, u := range users {
+			u.SuperLiked = super[u.ID]
+		}
+
+		s.setDistances(r, users...)
+		s.respond(w, http.StatusOK, store.UserPage{Users: users, NextCursor: likes.NextCursor})
+	}
+}
+
+// handlerSuperLikeQuota returns how many super likes the current user has
+// left today and when the quota resets.
+func (s *server) handlerSuperLikeQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerSuperLikeQuota()")
 
-			users = append(users, u)
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		q, err := s.store.Like().SuperLikeQuota(r.Context(), userID)
+		if err != nil {
+			s.respondError(w, r, "Cannot get super like quota:", err)
+			return
 		}
 
-		s.respond(w, http.StatusOK, users)
+		s.respond(w, http.StatusOK, q)
 	}
 }
 
@@ -478,28 +892,33 @@ func (s *server) handlerUserMathces() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Processing by handlerUserMathces()")
 
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		matches, err := s.store.Match().FindByUser(userID)
+		matches, err := s.store.Match().FindPageByUser(r.Context(), userID, p)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find matches:", err.Error())
+			s.respondError(w, r, "Cannot find matches:", err)
 			return
 		}
 
-		users := make([]*model.User, 0)
-		for _, v := range matches {
-			u, err := s.store.User().FindById(v.User2)
-			if err != nil {
-				s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-				s.err_logger.Println("Cannot find user:", err.Error())
-				return
-			}
+		ids := make([]int, 0, len(matches.Matches))
+		for _, v := range matches.Matches {
+			ids = append(ids, v.User2)
+		}
 
-			users = append(users, u)
+		users, err := s.store.User().FindByIDs(r.Context(), ids)
+		if err != nil {
+			s.respondError(w, r, "Cannot find users:", err)
+			return
 		}
 
-		s.respond(w, http.StatusOK, users)
+		s.setDistances(r, users...)
+		s.respond(w, http.StatusOK, store.UserPage{Users: users, NextCursor: matches.NextCursor})
 	}
 }
 
@@ -511,10 +930,9 @@ func (s *server) handlerUserCount() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Processing by handlerUserCount()")
 
-		usersCount, err := s.store.User().Count()
+		usersCount, err := s.store.User().Count(r.Context())
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot count users:", err.Error())
+			s.respondError(w, r, "Cannot count users:", err)
 			return
 		}
 
@@ -528,86 +946,389 @@ func (s *server) handlerUserDelete() http.HandlerFunc {
 
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		if err := s.store.Like().DeleteByUser(userID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete like:", err.Error())
-			return
-		}
+		err := s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := tx.Like().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		if err := s.store.Like().DeleteByLikedUser(userID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete like:", err.Error())
-			return
-		}
+			if err := tx.Like().DeleteByLikedUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		if err := s.store.Match().DeleteByUser(userID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete match:", err.Error())
-			return
-		}
+			matches, err := tx.Match().FindByUser(r.Context(), userID)
+			if err != nil {
+				return err
+			}
 
-		if err := s.store.User().DeleteById(userID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete user:", err.Error())
-			return
-		}
+			for _, m := range matches {
+				if err := outbox.Record(r.Context(), tx, model.DomainMatchRemoved, m); err != nil {
+					return err
+				}
+			}
 
-		if err := s.photoStore.DeleteByName(strconv.Itoa(userID)); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete user photo:", err.Error())
-			return
-		}
-	}
-}
+			if err := tx.Match().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-func (s *server) handlerUsersByFilter() http.HandlerFunc {
-	type filter struct {
-		MinAge int    `json:"min_age"`
-		MaxAge int    `json:"max_age"`
-		Gender string `json:"gender"`
-		City   string `json:"city"`
-	}
+			if err := tx.Pass().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		s.logger.Println("Processing by handlerUsersByFilter()")
+			if err := tx.Preferences().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
+
+			if err := tx.Unmatch().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		f := &filter{}
+			if err := tx.Counter().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		queryString := r.URL.Query().Get("")
+			if err := tx.Block().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		if err := json.NewDecoder(strings.NewReader(queryString)).Decode(f); err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Invalid filter data format:", err.Error())
-			return
-		}
+			if err := tx.Report().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
 
-		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+			if err := tx.Message().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
+
+			if err := tx.Event().DeleteByUser(r.Context(), userID); err != nil {
+				return err
+			}
+
+			if err := tx.User().DeleteById(r.Context(), userID); err != nil {
+				return err
+			}
+
+			return outbox.Record(r.Context(), tx, model.DomainUserDeleted, &model.DeletedUser{UserID: userID})
+		})
 
-		users, err := s.store.User().FindByFilters(userID, f.MinAge, f.MaxAge, f.Gender, f.City)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find users:", err.Error())
+			s.respondError(w, r, "Cannot delete user:", err)
 			return
 		}
 
-		s.respond(w, http.StatusOK, users)
+		// The file cannot be rolled back, so it is removed only once the
+		// user is. A file left behind is unreachable and only wastes space.
+		err = s.photoStore.DeleteByName(strconv.Itoa(userID))
+		if err != nil && err != store.ErrNotFound {
+			s.err_logger.Println(requestID(r), "Cannot delete photo:", err.Error())
+		}
 	}
 }
 
-func (s *server) handlerUsersAll() http.HandlerFunc {
+// handlerPreferences returns the discovery preferences of the current user,
+// which are empty until they are saved.
+func (s *server) handlerPreferences() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		s.logger.Println("Processing by handlerUsersAll()")
+		s.logger.Println("Processing by handlerPreferences()")
 
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
-		users, err := s.store.User().All(userID)
+		p, err := s.store.Preferences().FindByUser(r.Context(), userID)
+		if err == store.ErrNotFound {
+			p, err = &model.Preferences{UserID: userID}, nil
+		}
+		if err != nil {
+			s.respondError(w, r, "Cannot get preferences:", err)
+			return
+		}
+
+		s.respond(w, http.StatusOK, p)
+	}
+}
+
+func (s *server) handlerPreferencesUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerPreferencesUpdate()")
+
+		p := &model.Preferences{}
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid preferences data format:", err)
+			return
+		}
+
+		p.UserID = r.Context().Value(ctxUserKey).(*model.User).ID
+
+		if err := s.store.Preferences().Save(r.Context(), p); err != nil {
+			s.respondError(w, r, "Cannot save preferences:", err)
+			return
+		}
+
+		s.respond(w, http.StatusOK, p)
+	}
+}
+
+func (s *server) handlerLocationUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerLocationUpdate()")
+
+		l := &model.Location{}
+		if err := json.NewDecoder(r.Body).Decode(l); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid location data format:", err)
+			return
+		}
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		if err := s.store.User().UpdateLocation(r.Context(), userID, l); err != nil {
+			s.respondError(w, r, "Cannot update location:", err)
+			return
+		}
+	}
+}
+
+func (s *server) handlerLocationDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerLocationDelete()")
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		if err := s.store.User().UpdateLocation(r.Context(), userID, nil); err != nil {
+			s.respondError(w, r, "Cannot delete location:", err)
+			return
+		}
+	}
+}
+
+func (s *server) handlerUsersByFilter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerUsersByFilter()")
+
+		f, ok := s.parseUserFilter(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		page, err := s.store.User().FindByFilters(r.Context(), userID, f)
+		if err != nil {
+			s.respondError(w, r, "Cannot find users:", err)
+			return
+		}
+
+		s.setDistances(r, page.Users...)
+		s.respond(w, http.StatusOK, page)
+	}
+}
+
+// handlerFeed returns the swipe deck: the users matching the filter whom the
+// current user has not liked, passed or matched with yet. Unless the filter
+// asks for a sort order, every page is ordered by the ranker, and in debug
+// mode ?debug=true adds the score breakdown of every candidate.
+func (s *server) handlerFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerFeed()")
+
+		f, ok := s.parseUserFilter(w, r)
+		if !ok {
+			return
+		}
+
+		u := r.Context().Value(ctxUserKey).(*model.User)
+
+		if f.Sort != "" {
+			page, err := s.store.User().Feed(r.Context(), u.ID, f)
+			if err != nil {
+				s.respondError(w, r, "Cannot build feed:", err)
+				return
+			}
+
+			s.setDistances(r, page.Users...)
+			s.respond(w, http.StatusOK, &feedPage{UserPage: page})
+			return
+		}
+
+		res, err := s.rankedFeed(r.Context(), u, f)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find users:", err.Error())
+			s.respondError(w, r, "Cannot build feed:", err)
 			return
 		}
 
-		s.respond(w, http.StatusOK, users)
+		if !s.debug || r.URL.Query().Get("debug") != "true" {
+			res.Scores = nil
+		}
+
+		s.setDistances(r, res.Users...)
+		s.respond(w, http.StatusOK, res)
+	}
+}
+
+// rankedFeed ranks the s.feedWindow candidates of u seen most recently by
+// total score and
+// cuts the page that follows the cursor of f out of them.
+func (s *server) rankedFeed(ctx context.Context, u *model.User, f *store.UserFilter) (*feedPage, error) {
+	c := &ranking.Context{Viewer: u, Now: time.Now()}
+
+	var after *feedCursor
+	if f.Cursor != "" {
+		after = &feedCursor{}
+		if err := store.DecodeCursor(f.Cursor, after); err != nil {
+			return nil, err
+		}
+		c.Now = after.Now
+	}
+
+	wf := *f
+	wf.Sort = store.SortByLastSeen
+	wf.Limit = store.MaxLimit
+	wf.Cursor = ""
+
+	candidates := make([]*model.User, 0)
+	for len(candidates) < s.feedWindow {
+		page, err := s.store.User().Feed(ctx, u.ID, &wf)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, page.Users...)
+		if page.NextCursor == "" {
+			break
+		}
+		wf.Cursor = page.NextCursor
+	}
+	if len(candidates) > s.feedWindow {
+		candidates = candidates[:s.feedWindow]
+	}
+
+	ids := make([]int, 0, len(candidates))
+	for _, v := range candidates {
+		ids = append(ids, v.ID)
+	}
+
+	likers, err := s.store.Like().FindLikers(ctx, u.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	c.LikedBy = make(map[int]bool, len(likers))
+	for _, id := range likers {
+		c.LikedBy[id] = true
+	}
+
+	// Rank keeps the order of the candidates among equal scores, so they
+	// are put in the order of their ids first to end up ordered by
+	// (score desc, id).
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+	scores := s.ranker.Rank(c, candidates)
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(scores), func(i int) bool {
+			return scores[i].Total < after.Score || (scores[i].Total == after.Score && scores[i].UserID > after.ID)
+		})
+	}
+
+	end := start + f.PageLimit()
+	if end > len(candidates) {
+		end = len(candidates)
+	}
+
+	res := &feedPage{
+		UserPage: &store.UserPage{Users: candidates[start:end]},
+		Scores:   scores[start:end],
+	}
+
+	if end < len(candidates) {
+		last := scores[end-1]
+		res.NextCursor = store.EncodeCursor(feedCursor{Score: last.Total, ID: last.UserID, Now: c.Now})
+	}
+
+	return res, nil
+}
+
+// parseUserFilter reads a filter encoded as JSON in the unnamed query
+// parameter, with the legacy city field and the limit and cursor parameters
+// on top. Without the JSON filter the stored preferences of the current user
+// are used. It answers the request itself and returns false if the
+// parameters are invalid.
+func (s *server) parseUserFilter(w http.ResponseWriter, r *http.Request) (*store.UserFilter, bool) {
+	f := &struct {
+		store.UserFilter
+		City string `json:"city"`
+	}{}
+
+	queryString := r.URL.Query().Get("")
+
+	if queryString != "" {
+		if err := json.NewDecoder(strings.NewReader(queryString)).Decode(f); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid filter data format:", err)
+			return nil, false
+		}
+	} else {
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		p, err := s.store.Preferences().FindByUser(r.Context(), userID)
+		if err != nil && err != store.ErrNotFound {
+			s.respondError(w, r, "Cannot get preferences:", err)
+			return nil, false
+		}
+		if err == nil {
+			f.UserFilter = *store.NewUserFilter(p)
+		}
+
+		// The stored radius is only a default: it is skipped, not an
+		// error, while the user has no location.
+		if r.Context().Value(ctxUserKey).(*model.User).Location == nil {
+			f.MaxDistance = 0
+		}
+	}
+
+	f.Origin = r.Context().Value(ctxUserKey).(*model.User).Location
+
+	if f.City != "" {
+		f.Cities = append(f.Cities, f.City)
+	}
+
+	p, err := s.parsePagination(r)
+	if err != nil {
+		s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+		return nil, false
+	}
+	if p.Limit != 0 {
+		f.Limit = p.Limit
+	}
+	if p.Cursor != "" {
+		f.Cursor = p.Cursor
+	}
+
+	if err := f.Validate(); err != nil {
+		s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid filter:", err)
+		return nil, false
+	}
+
+	return &f.UserFilter, true
+}
+
+func (s *server) handlerUsersAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerUsersAll()")
+
+		p, err := s.parsePagination(r)
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeValidationFailed, "Invalid pagination:", err)
+			return
+		}
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		page, err := s.store.User().AllPage(r.Context(), userID, p)
+		if err != nil {
+			s.respondError(w, r, "Cannot find users:", err)
+			return
+		}
+
+		s.setDistances(r, page.Users...)
+		s.respond(w, http.StatusOK, page)
 	}
 }
 
@@ -619,15 +1340,17 @@ func (s *server) handlerPhoto() http.HandlerFunc {
 
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Indalid id:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		if !s.checkNotBlocked(w, r, id) {
 			return
 		}
 
 		p, err := s.photoStore.FindById(id)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot find user photo:", err.Error())
+			s.respondError(w, r, "Cannot find user photo:", err)
 			return
 		}
 
@@ -641,16 +1364,14 @@ func (s *server) handlerPhotoUpdate() http.HandlerFunc {
 
 		p, err := io.ReadAll(r.Body)
 		if err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Cannot read photo:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeBadRequest, "Cannot read photo:", err)
 			return
 		}
 
 		userID := r.Context().Value(ctxUserKey).(*model.User).ID
 
 		if err := s.photoStore.Create(p, userID); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot update photo:", err.Error())
+			s.respondError(w, r, "Cannot update photo:", err)
 			return
 		}
 	}
@@ -668,29 +1389,29 @@ func (s *server) handlerSessionCreate() http.HandlerFunc {
 		data := &request{}
 
 		if err := json.NewDecoder(r.Body).Decode(data); err != nil {
-			s.respond(w, http.StatusBadRequest, encd_err{err.Error()})
-			s.err_logger.Println("Invalid login and password data format:", err.Error())
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid login and password data format:", err)
 			return
 		}
 
-		u, err := s.store.User().FindByLogin(data.Login)
-		if err == pgx.ErrNoRows || !u.ComparePassword(data.Password) {
-			s.respond(w, http.StatusUnauthorized, encd_err{errWrongLoginOrPassword.Error()})
-			s.err_logger.Println("Wrong login or password:", errWrongLoginOrPassword.Error())
+		u, err := s.store.User().FindByLogin(r.Context(), data.Login)
+		if err != nil && err != store.ErrNotFound {
+			s.respondError(w, r, "Cannot find user:", err)
+			return
+		}
+		if err == store.ErrNotFound || !u.ComparePassword(data.Password) {
+			s.respondErrorCode(w, r, http.StatusUnauthorized, codeWrongCredentials, "Wrong login or password:", errWrongLoginOrPassword)
 			return
 		}
 
 		session, err := s.sessionStore.Get(r, sessionName)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot get session:", err.Error())
+			s.respondError(w, r, "Cannot get session:", err)
 			return
 		}
 
 		session.Values["user_id"] = u.ID
 		if err := s.sessionStore.Save(r, w, session); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot save session:", err.Error())
+			s.respondError(w, r, "Cannot save session:", err)
 			return
 		}
 	}
@@ -702,16 +1423,14 @@ func (s *server) handlerSessionDelete() http.HandlerFunc {
 
 		session, err := s.sessionStore.Get(r, sessionName)
 		if err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot get session:", err.Error())
+			s.respondError(w, r, "Cannot get session:", err)
 			return
 		}
 
 		session.Options.MaxAge = -1
 
 		if err := s.sessionStore.Save(r, w, session); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete session:", err.Error())
+			s.respondError(w, r, "Cannot delete session:", err)
 			return
 		}
 	}
@@ -723,13 +1442,207 @@ func (s *server) handlerLikeCreate() http.HandlerFunc {
 
 		l := r.Context().Value(ctxLikeKey).(*model.Like)
 
-		if err := s.store.Like().Create(l); err != nil {
-			s.respond(w, http.StatusInternalServerError, encd_err{err.Error()})
-			s.err_logger.Println("Cannot create like:", err.Error())
+		if !s.checkNotBlocked(w, r, l.LikedUser) {
+			return
+		}
+
+		u := r.Context().Value(ctxUserKey).(*model.User)
+		now := time.Now()
+
+		var m *model.Match
+		var events pendingEvents
+		err := s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := s.countLike(r.Context(), tx, u, now); err != nil {
+				return err
+			}
+
+			var err error
+			if m, err = tx.Like().Swipe(r.Context(), l); err != nil {
+				return err
+			}
+
+			if err := events.record(r.Context(), tx, l.LikedUser, eventLikeReceived, l); err != nil {
+				return err
+			}
+
+			if err := outbox.Record(r.Context(), tx, model.DomainLikeCreated, l); err != nil {
+				return err
+			}
+
+			if m == nil {
+				return nil
+			}
+
+			if err := outbox.Record(r.Context(), tx, model.DomainMatchCreated, m); err != nil {
+				return err
+			}
+			return events.recordMatch(r.Context(), tx, eventMatchCreated, m)
+		})
+
+		if err != nil {
+			s.respondLikeError(w, r, now, err)
+			return
+		}
+
+		s.push(r.Context(), events)
+
+		s.respond(w, http.StatusCreated, swipeResult{
+			Like:    l,
+			Matched: m != nil,
+			Match:   m,
+		})
+	}
+}
+
+func (s *server) handlerPassCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerPassCreate()")
+
+		p := &model.Pass{}
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid pass data format:", err)
+			return
+		}
+
+		p.UserID = r.Context().Value(ctxUserKey).(*model.User).ID
+
+		if !s.checkNotBlocked(w, r, p.PassedUser) {
+			return
+		}
+
+		if err := s.store.Pass().Create(r.Context(), p); err != nil {
+			s.respondError(w, r, "Cannot create pass:", err)
+			return
+		}
+
+		s.respond(w, http.StatusCreated, p)
+	}
+}
+
+// handlerBlockCreate blocks the user from the body for the current user.
+// The likes between the two and their match are dropped along with it.
+func (s *server) handlerBlockCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerBlockCreate()")
+
+		b := &model.Block{}
+		if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid block data format:", err)
+			return
+		}
+
+		b.UserID = r.Context().Value(ctxUserKey).(*model.User).ID
+
+		var events pendingEvents
+		err := s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := tx.Block().Create(r.Context(), b); err != nil {
+				return err
+			}
+
+			for _, pair := range [][2]int{{b.UserID, b.BlockedUser}, {b.BlockedUser, b.UserID}} {
+				err := tx.Like().DeleteByUsers(r.Context(), pair[0], pair[1])
+				if err != nil && err != store.ErrNotFound {
+					return err
+				}
+			}
+
+			_, err := dissolveMatch(r.Context(), tx, &events, b.UserID, b.BlockedUser)
+			return err
+		})
+
+		if err != nil {
+			s.respondError(w, r, "Cannot create block:", err)
+			return
+		}
+
+		s.push(r.Context(), events)
+
+		s.respond(w, http.StatusCreated, b)
+	}
+}
+
+// handlerReportCreate puts a report of the current user into the moderation
+// queue.
+func (s *server) handlerReportCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerReportCreate()")
+
+		rep := &model.Report{}
+		if err := json.NewDecoder(r.Body).Decode(rep); err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid report data format:", err)
+			return
+		}
+
+		rep.UserID = r.Context().Value(ctxUserKey).(*model.User).ID
+
+		if err := s.store.Report().Create(r.Context(), rep); err != nil {
+			s.respondError(w, r, "Cannot create report:", err)
+			return
+		}
+
+		s.respond(w, http.StatusCreated, rep)
+	}
+}
+
+// handlerSwipeRewind undoes the most recent like or pass of the current user
+// if it is still within the rewind window. The match a like is part of is
+// dissolved along with it.
+func (s *server) handlerSwipeRewind() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerSwipeRewind()")
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+		res := &rewindResult{}
+
+		var events pendingEvents
+		err := s.store.WithTx(r.Context(), func(tx store.Store) error {
+			l, err := tx.Like().FindLastByUser(r.Context(), userID)
+			if err != nil && err != store.ErrNotFound {
+				return err
+			}
+
+			p, err := tx.Pass().FindLastByUser(r.Context(), userID)
+			if err != nil && err != store.ErrNotFound {
+				return err
+			}
+
+			if p != nil && (l == nil || p.CreatedAt.After(l.CreatedAt)) {
+				if time.Since(p.CreatedAt) > s.rewindWindow {
+					return errRewindExpired
+				}
+
+				res.Pass = p
+				return tx.Pass().DeleteByID(r.Context(), p.ID)
+			}
+
+			if l == nil {
+				return store.ErrNotFound
+			}
+
+			if time.Since(l.CreatedAt) > s.rewindWindow {
+				return errRewindExpired
+			}
+
+			res.Like = l
+			if err := tx.Like().DeleteByUsers(r.Context(), l.UserID, l.LikedUser); err != nil {
+				return err
+			}
+
+			res.Match, err = dissolveMatch(r.Context(), tx, &events, l.UserID, l.LikedUser)
+			return err
+		})
+
+		if err == errRewindExpired {
+			s.respondErrorCode(w, r, http.StatusConflict, codeRewindExpired, "Cannot rewind swipe:", err)
+			return
+		}
+		if err != nil {
+			s.respondError(w, r, "Cannot rewind swipe:", err)
 			return
 		}
 
-		s.respond(w, http.StatusCreated, l)
+		s.push(r.Context(), events)
+		s.respond(w, http.StatusOK, res)
 	}
 }
 
@@ -739,16 +1652,127 @@ func (s *server) handlerLikeDelete() http.HandlerFunc {
 
 		l := r.Context().Value(ctxLikeKey).(*model.Like)
 
-		if err := s.store.Like().DeleteByUsers(l.UserID, l.LikedUser); err != nil {
-			s.respond(w, http.StatusUnprocessableEntity, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete like:", err.Error())
+		var events pendingEvents
+		err := s.store.WithTx(r.Context(), func(tx store.Store) error {
+			if err := tx.Like().DeleteByUsers(r.Context(), l.UserID, l.LikedUser); err != nil {
+				return err
+			}
+
+			_, err := dissolveMatch(r.Context(), tx, &events, l.UserID, l.LikedUser)
+			return err
+		})
+
+		if err != nil {
+			s.respondError(w, r, "Cannot delete like:", err)
+			return
+		}
+
+		s.push(r.Context(), events)
+	}
+}
+
+// handlerMatchDelete dissolves the match of the current user with the user
+// from the path. The current user's like is withdrawn too, so the pair does
+// not match again right away, and the unmatch is recorded for both of them.
+func (s *server) handlerMatchDelete() http.HandlerFunc {
+	type request struct {
+		Reason string `json:"reason"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerMatchDelete()")
+
+		userID := r.Context().Value(ctxUserKey).(*model.User).ID
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
 			return
 		}
 
-		if err := s.store.Match().DeleteByUser(l.UserID); err != nil {
-			s.respond(w, http.StatusUnprocessableEntity, encd_err{err.Error()})
-			s.err_logger.Println("Cannot delete match:", err.Error())
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid unmatch data format:", err)
 			return
 		}
+
+		var events pendingEvents
+		err = s.store.WithTx(r.Context(), func(tx store.Store) error {
+			m, err := dissolveMatch(r.Context(), tx, &events, userID, id)
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				return store.ErrNotFound
+			}
+
+			err = tx.Like().DeleteByUsers(r.Context(), userID, id)
+			if err != nil && err != store.ErrNotFound {
+				return err
+			}
+
+			return tx.Unmatch().Create(r.Context(), &model.Unmatch{
+				UserID:        userID,
+				UnmatchedUser: id,
+				Reason:        req.Reason,
+			})
+		})
+
+		if err != nil {
+			s.respondError(w, r, "Cannot delete match:", err)
+			return
+		}
+
+		s.push(r.Context(), events)
+	}
+}
+
+// handlerAdminLimits shows the like limits and the current counters of the
+// user from the path.
+func (s *server) handlerAdminLimits() http.HandlerFunc {
+	type limit struct {
+		Name   string `json:"name"`
+		Max    int    `json:"max"`
+		Period string `json:"period"`
+	}
+
+	type responce struct {
+		Limits   []limit          `json:"limits"`
+		Counters []*model.Counter `json:"counters"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerAdminLimits()")
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.respondErrorCode(w, r, http.StatusBadRequest, codeInvalidID, "Indalid id:", err)
+			return
+		}
+
+		if _, err := s.store.User().FindById(r.Context(), id); err != nil {
+			s.respondError(w, r, "Cannot find user:", err)
+			return
+		}
+
+		counters, err := s.store.Counter().FindByUser(r.Context(), id)
+		if err != nil {
+			s.respondError(w, r, "Cannot find counters:", err)
+			return
+		}
+
+		res := responce{
+			Limits:   make([]limit, 0, len(s.likeLimits)),
+			Counters: counters,
+		}
+		for _, l := range s.likeLimits {
+			period := "day"
+			if l.Period > 0 {
+				period = l.Period.String()
+			}
+			res.Limits = append(res.Limits, limit{Name: l.Name, Max: l.Max, Period: period})
+		}
+
+		s.respond(w, http.StatusOK, res)
 	}
 }
@@ -0,0 +1,1839 @@
+package apiserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/gorilla/websocket"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/pubsub"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegHeader is enough for http.DetectContentType to report image/jpeg.
+var jpegHeader = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00}
+
+func testServer(t *testing.T) (*server, *teststore.Store) {
+	t.Helper()
+
+	st := teststore.New()
+	s := newServer(st, teststore.NewPhotoStore(), sessions.NewCookieStore([]byte("secret")))
+
+	return s, st
+}
+
+func testUser(t *testing.T) *model.User {
+	t.Helper()
+
+	return &model.User{
+		Login:       "valid_login",
+		Password:    "valid_password",
+		Name:        "valid_name",
+		Age:         20,
+		Gender:      "male",
+		City:        "valid_city",
+		PhoneNumber: "+79999999999",
+		About:       "example text",
+	}
+}
+
+// login creates a session for u and returns its cookie.
+func login(t *testing.T, s *server, u *model.User) *http.Cookie {
+	t.Helper()
+
+	b := &bytes.Buffer{}
+	json.NewEncoder(b).Encode(map[string]string{
+		"login":    u.Login,
+		"password": u.Password,
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sessions", b))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cannot log in: %d %s", rec.Code, rec.Body.String())
+	}
+
+	return rec.Result().Cookies()[0]
+}
+
+func TestServer_HandlerUserCreate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		user         func() *model.User
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			user: func() *model.User {
+				return testUser(t)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name: "invalid user",
+			user: func() *model.User {
+				u := testUser(t)
+				u.Age = 17
+				return u
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := testServer(t)
+
+			b := &bytes.Buffer{}
+			mw := multipart.NewWriter(b)
+
+			up, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+			json.NewEncoder(up).Encode(tc.user())
+			pp, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"image/jpeg"}})
+			pp.Write(jpegHeader)
+			mw.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/users", b)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_HandlerUserCreateInvalidPhoto(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	create := func(photo []byte) int {
+		b := &bytes.Buffer{}
+		mw := multipart.NewWriter(b)
+
+		up, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+		json.NewEncoder(up).Encode(testUser(t))
+		pp, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"image/jpeg"}})
+		pp.Write(photo)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/users", b)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	// A photo that cannot be saved leaves neither the user nor its
+	// registration behind, so the user can try again.
+	assert.Equal(t, http.StatusUnprocessableEntity, create([]byte("not a jpeg")))
+	_, err := st.User().FindByLogin(ctx, testUser(t).Login)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	pending, err := st.Outbox().FindByStatus(ctx, model.OutboxPending, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.Equal(t, http.StatusCreated, create(jpegHeader))
+}
+
+func TestServer_HandlerUserUpdate(t *testing.T) {
+	s, st := testServer(t)
+	u := testUser(t)
+	assert.NoError(t, st.User().Create(context.Background(), u))
+	u.Password = testUser(t).Password
+	cookie := login(t, s, u)
+
+	testCases := []struct {
+		name         string
+		payload      map[string]interface{}
+		expectedCode int
+	}{
+		{
+			name:         "timezone",
+			payload:      map[string]interface{}{"timezone": "Europe/Moscow"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown timezone",
+			payload:      map[string]interface{}{"timezone": "Mars/Olympus"},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "interests",
+			payload:      map[string]interface{}{"interests": []string{"music", "hiking"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "too many interests",
+			payload:      map[string]interface{}{"interests": strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "too long interest",
+			payload:      map[string]interface{}{"interests": []string{strings.Repeat("a", 26)}},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			json.NewEncoder(b).Encode(tc.payload)
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/current", b)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	// Nothing invalid is stored.
+	found, err := st.User().FindById(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Moscow", found.Timezone)
+	assert.Equal(t, []string{"music", "hiking"}, found.Interests)
+}
+
+func TestServer_HandlerSessionCreate(t *testing.T) {
+	s, st := testServer(t)
+	u := testUser(t)
+	password := u.Password
+	assert.NoError(t, st.User().Create(context.Background(), u))
+
+	testCases := []struct {
+		name         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			payload: map[string]string{
+				"login":    u.Login,
+				"password": password,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "wrong password",
+			payload: map[string]string{
+				"login":    u.Login,
+				"password": "wrong_password",
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid payload",
+			payload:      "invalid",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			json.NewEncoder(b).Encode(tc.payload)
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sessions", b))
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_AuthenticateUser(t *testing.T) {
+	s, st := testServer(t)
+	u := testUser(t)
+	assert.NoError(t, st.User().Create(context.Background(), u))
+	u.Password = testUser(t).Password
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/current", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/current", nil)
+	req.AddCookie(login(t, s, u))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_HandlerLikeCreate(t *testing.T) {
+	s, st := testServer(t)
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_login"
+	u2.PhoneNumber = "+79999999991"
+	assert.NoError(t, st.User().Create(context.Background(), u1))
+	assert.NoError(t, st.User().Create(context.Background(), u2))
+	u1.Password = testUser(t).Password
+	u2.Password = testUser(t).Password
+
+	like := func(from, to *model.User) (int, *swipeResult) {
+		b := &bytes.Buffer{}
+		json.NewEncoder(b).Encode(map[string]int{"liked_user": to.ID})
+
+		req := httptest.NewRequest(http.MethodPost, "/likes", b)
+		req.AddCookie(login(t, s, from))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		res := &swipeResult{}
+		json.NewDecoder(rec.Body).Decode(res)
+
+		return rec.Code, res
+	}
+
+	code, res := like(u1, u2)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, res.Matched)
+	assert.Nil(t, res.Match)
+	matches, err := st.Match().FindByUser(context.Background(), u1.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	code, res = like(u2, u1)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, res.Matched)
+	if assert.NotNil(t, res.Match) {
+		assert.Equal(t, u2.ID, res.Match.User1)
+		assert.Equal(t, u1.ID, res.Match.User2)
+	}
+	matches, err = st.Match().FindByUser(context.Background(), u1.ID)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
+
+// brokenPhotoStore fails every deletion.
+type brokenPhotoStore struct {
+	*teststore.PhotoStore
+}
+
+func (ps brokenPhotoStore) DeleteByName(fileName string) error {
+	return errors.New("disk failure")
+}
+
+// rollbackStore fails every transaction after its statements succeed, as a
+// failed commit would.
+type rollbackStore struct {
+	store.Store
+}
+
+func (s rollbackStore) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx store.Store) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return errors.New("commit failed")
+	})
+}
+
+func TestServer_HandlerUserDelete(t *testing.T) {
+	s, st := testServer(t)
+	ps := s.photoStore
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_login"
+	u2.PhoneNumber = "+79999999991"
+	assert.NoError(t, st.User().Create(context.Background(), u1))
+	assert.NoError(t, st.User().Create(context.Background(), u2))
+	u1.Password = testUser(t).Password
+	assert.NoError(t, st.Like().Create(context.Background(), &model.Like{UserID: u1.ID, LikedUser: u2.ID}))
+	assert.NoError(t, st.Like().Create(context.Background(), &model.Like{UserID: u2.ID, LikedUser: u1.ID}))
+	assert.NoError(t, st.Match().Create(context.Background(), &model.Match{User1: u1.ID, User2: u2.ID}))
+	assert.NoError(t, ps.Create(jpegHeader, u1.ID))
+
+	cookie := login(t, s, u1)
+	del := func() int {
+		req := httptest.NewRequest(http.MethodDelete, "/users/current", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A transaction that fails keeps both the user and the photo.
+	s.store = rollbackStore{st}
+	assert.Equal(t, http.StatusInternalServerError, del())
+	likes, err := st.Like().FindByUserID(context.Background(), u1.ID)
+	assert.NoError(t, err)
+	assert.Len(t, likes, 1)
+	_, err = ps.FindById(u1.ID)
+	assert.NoError(t, err)
+
+	s.store = st
+	assert.Equal(t, http.StatusOK, del())
+	_, err = ps.FindById(u1.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	count, err := st.User().Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	matches, err := st.Match().FindByUser(context.Background(), u2.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	// The photo goes after the user, so failing to delete it does not
+	// bring the user back.
+	cookie = login(t, s, u2)
+	s.photoStore = brokenPhotoStore{teststore.NewPhotoStore()}
+	assert.Equal(t, http.StatusOK, del())
+	count, err = st.User().Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestServer_HandlerUsersAll(t *testing.T) {
+	s, st := testServer(t)
+
+	users := make([]*model.User, 4)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(context.Background(), u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	cookie := login(t, s, users[0])
+
+	get := func(query string) (int, *store.UserPage) {
+		req := httptest.NewRequest(http.MethodGet, "/users/all"+query, nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		page := &store.UserPage{}
+		json.NewDecoder(rec.Body).Decode(page)
+		return rec.Code, page
+	}
+
+	code, page := get("?limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, users[1].ID, page.Users[0].ID)
+	assert.NotEmpty(t, page.NextCursor)
+
+	code, page = get("?limit=2&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Users, 1)
+	assert.Equal(t, users[3].ID, page.Users[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	code, _ = get("?cursor=invalid")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get("?limit=1000")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestServer_StoreErrors(t *testing.T) {
+	s, st := testServer(t)
+
+	u := testUser(t)
+	assert.NoError(t, st.User().Create(context.Background(), u))
+	u.Password = testUser(t).Password
+	cookie := login(t, s, u)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name:         "unknown user",
+			method:       http.MethodGet,
+			path:         "/users/100",
+			expectedCode: http.StatusNotFound,
+			expectedErr:  codeNotFound,
+		},
+		{
+			name:         "self like",
+			method:       http.MethodPost,
+			path:         "/likes",
+			payload:      map[string]int{"liked_user": u.ID},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedErr:  codeSelfLike,
+		},
+		{
+			name:         "like of unknown user",
+			method:       http.MethodPost,
+			path:         "/likes",
+			payload:      map[string]int{"liked_user": 100},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  codeNotFound,
+		},
+		{
+			name:         "withdraw missing like",
+			method:       http.MethodDelete,
+			path:         "/likes",
+			payload:      map[string]int{"liked_user": 100},
+			expectedCode: http.StatusNotFound,
+			expectedErr:  codeNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(b).Encode(tc.payload)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, b)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			e := &encd_err{}
+			json.NewDecoder(rec.Body).Decode(e)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, tc.expectedErr, e.Code)
+		})
+	}
+}
+
+func TestServer_ErrorEnvelope(t *testing.T) {
+	s, _ := testServer(t)
+
+	u := testUser(t)
+	u.Age = 17
+
+	b := &bytes.Buffer{}
+	mw := multipart.NewWriter(b)
+	up, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	json.NewEncoder(up).Encode(u)
+	pp, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"image/jpeg"}})
+	pp.Write(jpegHeader)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", b)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-Request-ID", "test-request")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	e := &encd_err{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, codeValidationFailed, e.Code)
+	assert.NotEmpty(t, e.Message)
+	assert.Contains(t, e.Fields, "age")
+	assert.Equal(t, "test-request", e.RequestID)
+	assert.Equal(t, "test-request", rec.Header().Get("X-Request-ID"))
+
+	req = httptest.NewRequest(http.MethodGet, "/users/current", nil)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	e = &encd_err{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, codeUnauthorized, e.Code)
+	assert.NotEmpty(t, e.RequestID)
+	assert.Equal(t, e.RequestID, rec.Header().Get("X-Request-ID"))
+}
+
+func TestServer_HandlerMatchDelete(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1, u2, u3 := users[0], users[1], users[2]
+
+	for _, other := range []*model.User{u2, u3} {
+		_, err := st.Like().Swipe(ctx, &model.Like{UserID: u1.ID, LikedUser: other.ID})
+		assert.NoError(t, err)
+		_, err = st.Like().Swipe(ctx, &model.Like{UserID: other.ID, LikedUser: u1.ID})
+		assert.NoError(t, err)
+	}
+
+	unmatch := func(id int, payload interface{}) int {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/matches/%d", id), b)
+		req.AddCookie(login(t, s, u1))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, unmatch(u2.ID, map[string]string{"reason": "no reply"}))
+
+	matches, err := st.Match().FindByUser(ctx, u1.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, u3.ID, matches[0].User2)
+	}
+
+	_, err = st.Like().FindMatchLike(ctx, &model.Like{UserID: u2.ID, LikedUser: u1.ID})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = st.Like().FindMatchLike(ctx, &model.Like{UserID: u1.ID, LikedUser: u2.ID})
+	assert.NoError(t, err)
+
+	unmatches, err := st.Unmatch().FindByUser(ctx, u2.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, unmatches, 1) {
+		assert.Equal(t, u1.ID, unmatches[0].UserID)
+		assert.Equal(t, "no reply", unmatches[0].Reason)
+	}
+
+	assert.Equal(t, http.StatusNotFound, unmatch(u2.ID, nil))
+}
+
+func TestServer_HandlerFeed(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 5)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1 := users[0]
+
+	assert.NoError(t, st.Like().Create(ctx, &model.Like{UserID: u1.ID, LikedUser: users[1].ID}))
+	assert.NoError(t, st.Match().Create(ctx, &model.Match{User1: users[3].ID, User2: u1.ID}))
+	// Being liked does not hide anyone from the feed.
+	assert.NoError(t, st.Like().Create(ctx, &model.Like{UserID: users[4].ID, LikedUser: u1.ID}))
+
+	b := &bytes.Buffer{}
+	json.NewEncoder(b).Encode(map[string]int{"passed_user": users[2].ID})
+	req := httptest.NewRequest(http.MethodPost, "/passes", b)
+	req.AddCookie(login(t, s, u1))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/feed", nil)
+	req.AddCookie(login(t, s, u1))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	page := &store.UserPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, users[4].ID, page.Users[0].ID)
+	}
+}
+
+func TestServer_HandlerPreferences(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		u.Age = 20 + i*10
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1 := users[0]
+	cookie := login(t, s, u1)
+
+	do := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := do(http.MethodPut, "/users/current/preferences", map[string]int{"min_age": 40, "max_age": 30})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = do(http.MethodPut, "/users/current/preferences", map[string]int{"min_age": 25})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(http.MethodGet, "/users/current/preferences", nil)
+	p := &model.Preferences{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(p))
+	assert.Equal(t, 25, p.MinAge)
+
+	// Without an explicit filter the feed follows the preferences.
+	rec = do(http.MethodGet, "/feed", nil)
+	page := &store.UserPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	assert.Len(t, page.Users, 2)
+
+	// The oldest user only wants people over 25, so a mutual feed skips them.
+	assert.NoError(t, st.Preferences().Save(ctx, &model.Preferences{UserID: users[2].ID, MinAge: 25}))
+	do(http.MethodPut, "/users/current/preferences", map[string]interface{}{"min_age": 25, "mutual": true})
+
+	rec = do(http.MethodGet, "/feed", nil)
+	page = &store.UserPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, users[1].ID, page.Users[0].ID)
+	}
+}
+
+func TestServer_Location(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1 := users[0]
+	cookie := login(t, s, u1)
+
+	assert.NoError(t, st.User().UpdateLocation(ctx, users[1].ID, &model.Location{Latitude: 55.78, Longitude: 37.60}))
+	assert.NoError(t, st.User().UpdateLocation(ctx, users[2].ID, &model.Location{Latitude: 59.93, Longitude: 30.36}))
+
+	do := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	filter := "/users/filter?=" + url.QueryEscape(`{"max_distance": 50}`)
+
+	rec := do(http.MethodGet, filter, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = do(http.MethodPut, "/users/current/location", map[string]float64{"latitude": 91, "longitude": 0})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = do(http.MethodPut, "/users/current/location", map[string]float64{"latitude": 55.75, "longitude": 37.62})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(http.MethodGet, filter, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "latitude")
+
+	page := &store.UserPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, users[1].ID, page.Users[0].ID)
+		// The distance is between the cells of the grid, not the exact points.
+		assert.Equal(t, 5, page.Users[0].Distance)
+	}
+}
+
+func TestServer_HandlerFeedRanking(t *testing.T) {
+	s, st := testServer(t)
+	s.debug = true
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1 := users[0]
+
+	assert.NoError(t, st.Like().Create(ctx, &model.Like{UserID: users[2].ID, LikedUser: u1.ID}))
+
+	feed := func(path string) *feedPage {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(login(t, s, u1))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		page := &feedPage{}
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+		return page
+	}
+
+	page := feed("/feed")
+	if assert.Len(t, page.Users, 2) {
+		assert.Equal(t, users[2].ID, page.Users[0].ID)
+	}
+	assert.Empty(t, page.Scores)
+
+	page = feed("/feed?debug=true")
+	if assert.Len(t, page.Scores, 2) {
+		assert.Equal(t, users[2].ID, page.Scores[0].UserID)
+		assert.Contains(t, page.Scores[0].Breakdown, "liked_you")
+	}
+
+	// The whole feed is ranked, not each page, so the best candidate comes
+	// first even though it has the largest id.
+	ids := make([]int, 0)
+	path := "/feed?limit=1"
+	for {
+		page := feed(path)
+		for _, v := range page.Users {
+			ids = append(ids, v.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/feed?limit=1&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, []int{users[2].ID, users[1].ID}, ids)
+
+	// The window holds the candidates seen most recently, not the ones with
+	// the smallest ids.
+	s.feedWindow = 1
+	assert.NoError(t, st.User().UpdateLastSeen(ctx, users[2].ID, time.Now()))
+	page = feed("/feed")
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, users[2].ID, page.Users[0].ID)
+	}
+}
+
+func TestServer_SuperLikes(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+
+	do := func(from *model.User, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(login(t, s, from))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := do(users[1], http.MethodPost, "/likes", map[string]interface{}{"liked_user": users[0].ID, "type": "super"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do(users[1], http.MethodPost, "/likes", map[string]interface{}{"liked_user": users[2].ID, "type": "super"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	rec = do(users[1], http.MethodGet, "/users/current/super_likes", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	q := &model.Quota{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(q))
+	assert.Equal(t, 1, q.Used)
+	assert.Equal(t, 0, q.Remaining)
+
+	rec = do(users[2], http.MethodPost, "/likes", map[string]interface{}{"liked_user": users[0].ID})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = do(users[0], http.MethodGet, "/users/liked_by", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page := &store.UserPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Users, 2) {
+		assert.True(t, page.Users[0].SuperLiked)
+		assert.False(t, page.Users[1].SuperLiked)
+	}
+}
+
+func TestServer_ThrottleLikes(t *testing.T) {
+	s, st := testServer(t)
+	s.likeLimits = []*model.Limit{{Name: limitLikesBurst, Max: 2, Period: time.Hour}}
+	s.adminToken = "admin_token"
+	ctx := context.Background()
+
+	users := make([]*model.User, 4)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	cookie := login(t, s, users[0])
+
+	like := func(to *model.User) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		json.NewEncoder(b).Encode(map[string]int{"liked_user": to.ID})
+
+		req := httptest.NewRequest(http.MethodPost, "/likes", b)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	// Likes that fail do not count.
+	assert.Equal(t, http.StatusNotFound, like(&model.User{ID: 1000}).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, like(users[0]).Code)
+
+	assert.Equal(t, http.StatusCreated, like(users[1]).Code)
+	assert.Equal(t, http.StatusConflict, like(users[1]).Code)
+	assert.Equal(t, http.StatusCreated, like(users[2]).Code)
+
+	rec := like(users[3])
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	e := &encd_err{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+	assert.Equal(t, codeLimitExceeded, e.Code)
+
+	admin := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/users/%d/limits", users[0].ID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, admin("wrong").Code)
+
+	rec = admin("admin_token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	res := &struct {
+		Limits   []map[string]interface{} `json:"limits"`
+		Counters []*model.Counter         `json:"counters"`
+	}{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+	assert.Len(t, res.Limits, 1)
+	if assert.Len(t, res.Counters, 1) {
+		assert.Equal(t, 2, res.Counters[0].Count)
+	}
+}
+
+func TestServer_ThrottleLikesTimezoneChange(t *testing.T) {
+	s, st := testServer(t)
+	s.likeLimits = []*model.Limit{{Name: limitLikesDaily, Max: 1}}
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	cookie := login(t, s, users[0])
+
+	do := func(method, path string, payload interface{}) int {
+		b := &bytes.Buffer{}
+		json.NewEncoder(b).Encode(payload)
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/likes", map[string]int{"liked_user": users[1].ID}))
+
+	// Another timezone has another midnight, but the day that has begun
+	// goes on.
+	assert.Equal(t, http.StatusOK, do(http.MethodPatch, "/users/current", map[string]string{"timezone": "Pacific/Kiritimati"}))
+	assert.Equal(t, http.StatusTooManyRequests, do(http.MethodPost, "/likes", map[string]int{"liked_user": users[2].ID}))
+}
+
+func TestServer_HandlerSwipeRewind(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+	}
+	u1, u2, u3 := users[0], users[1], users[2]
+	cookie := login(t, s, u1)
+
+	do := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	_, err := st.Like().Swipe(ctx, &model.Like{UserID: u2.ID, LikedUser: u1.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/likes", map[string]int{"liked_user": u2.ID}).Code)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/passes", map[string]int{"passed_user": u3.ID}).Code)
+
+	rec := do(http.MethodPost, "/swipes/rewind", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	res := &rewindResult{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+	if assert.NotNil(t, res.Pass) {
+		assert.Equal(t, u3.ID, res.Pass.PassedUser)
+	}
+	assert.Nil(t, res.Like)
+
+	rec = do(http.MethodPost, "/swipes/rewind", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	res = &rewindResult{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(res))
+	if assert.NotNil(t, res.Like) {
+		assert.Equal(t, u2.ID, res.Like.LikedUser)
+	}
+	assert.NotNil(t, res.Match)
+
+	matches, err := st.Match().FindByUser(ctx, u1.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	_, err = st.Like().FindMatchLike(ctx, &model.Like{UserID: u2.ID, LikedUser: u1.ID})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/swipes/rewind", nil).Code)
+
+	s.rewindWindow = 0
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/passes", map[string]int{"passed_user": u3.ID}).Code)
+	rec = do(http.MethodPost, "/swipes/rewind", nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	e := &encd_err{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+	assert.Equal(t, codeRewindExpired, e.Code)
+}
+
+func TestServer_Blocks(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	cookies := make([]*http.Cookie, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s, u)
+	}
+	u1, u2, u3 := users[0], users[1], users[2]
+
+	do := func(i int, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookies[i])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	userIDs := func(i int, path string) []int {
+		rec := do(i, http.MethodGet, path, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		page := &store.UserPage{}
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+
+		ids := make([]int, 0, len(page.Users))
+		for _, u := range page.Users {
+			ids = append(ids, u.ID)
+		}
+		return ids
+	}
+
+	_, err := st.Like().Swipe(ctx, &model.Like{UserID: u1.ID, LikedUser: u3.ID})
+	assert.NoError(t, err)
+	_, err = st.Like().Swipe(ctx, &model.Like{UserID: u3.ID, LikedUser: u1.ID})
+	assert.NoError(t, err)
+	_, err = st.Like().Swipe(ctx, &model.Like{UserID: u2.ID, LikedUser: u1.ID})
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []int{u3.ID}, userIDs(0, "/users/matches"))
+	assert.ElementsMatch(t, []int{u2.ID, u3.ID}, userIDs(0, "/users/liked_by"))
+
+	rec := do(0, http.MethodPost, "/blocks", map[string]interface{}{"blocked_user": u3.ID, "reason": "rude"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, http.StatusCreated, do(1, http.MethodPost, "/blocks", map[string]int{"blocked_user": u1.ID}).Code)
+
+	matches, err := st.Match().FindByUser(ctx, u1.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	_, err = st.Like().FindMatchLike(ctx, &model.Like{UserID: u3.ID, LikedUser: u1.ID})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	assert.Empty(t, userIDs(0, "/users/matches"))
+	assert.Empty(t, userIDs(0, "/users/liked_by"))
+	assert.Empty(t, userIDs(0, "/users/all"))
+	assert.ElementsMatch(t, []int{u3.ID}, userIDs(1, "/users/all"))
+
+	for i, path := range []string{
+		fmt.Sprintf("/users/%d", u3.ID),
+		fmt.Sprintf("/photos/%d", u2.ID),
+	} {
+		assert.Equal(t, http.StatusNotFound, do(0, http.MethodGet, path, nil).Code, i)
+	}
+	assert.Equal(t, http.StatusNotFound, do(2, http.MethodGet, fmt.Sprintf("/users/%d", u1.ID), nil).Code)
+	assert.Equal(t, http.StatusOK, do(1, http.MethodGet, fmt.Sprintf("/users/%d", u3.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(2, http.MethodPost, "/likes", map[string]int{"liked_user": u1.ID}).Code)
+
+	testCases := []struct {
+		name         string
+		path         string
+		payload      interface{}
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name:         "already blocked",
+			path:         "/blocks",
+			payload:      map[string]int{"blocked_user": u3.ID},
+			expectedCode: http.StatusConflict,
+			expectedErr:  codeAlreadyBlocked,
+		},
+		{
+			name:         "self block",
+			path:         "/blocks",
+			payload:      map[string]int{"blocked_user": u1.ID},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedErr:  codeSelfBlock,
+		},
+		{
+			name:         "report",
+			path:         "/reports",
+			payload:      map[string]interface{}{"reported_user": u3.ID, "reason": model.ReportReasonHarassment},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "self report",
+			path:         "/reports",
+			payload:      map[string]interface{}{"reported_user": u1.ID, "reason": model.ReportReasonSpam},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedErr:  codeSelfReport,
+		},
+		{
+			name:         "unknown reason",
+			path:         "/reports",
+			payload:      map[string]interface{}{"reported_user": u2.ID, "reason": "boring"},
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedErr:  codeValidationFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(0, http.MethodPost, tc.path, tc.payload)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedErr != "" {
+				e := &encd_err{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+				assert.Equal(t, tc.expectedErr, e.Code)
+			}
+		})
+	}
+
+	// Reports outlive the account of the reported user, but not the one of
+	// the reporter.
+	assert.Equal(t, http.StatusOK, do(2, http.MethodDelete, "/users/current", nil).Code)
+	reports, err := st.Report().FindByReportedUser(ctx, u3.ID)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1)
+
+	assert.Equal(t, http.StatusOK, do(0, http.MethodDelete, "/users/current", nil).Code)
+	reports, err = st.Report().FindByReportedUser(ctx, u3.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, reports)
+}
+
+func TestServer_Messages(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	cookies := make([]*http.Cookie, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s, u)
+	}
+
+	do := func(i int, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookies[i])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	_, err := st.Like().Swipe(ctx, &model.Like{UserID: users[0].ID, LikedUser: users[1].ID})
+	assert.NoError(t, err)
+	match, err := st.Like().Swipe(ctx, &model.Like{UserID: users[1].ID, LikedUser: users[0].ID})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/matches/%d/messages", match.ID)
+
+	for i, body := range []string{"hi", "hello", "how are you?"} {
+		rec := do(i%2, http.MethodPost, path, messageRequest{Body: body})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, do(0, http.MethodPost, path, messageRequest{}).Code)
+	assert.Equal(t, http.StatusNotFound, do(2, http.MethodPost, path, messageRequest{Body: "hi"}).Code)
+	assert.Equal(t, http.StatusNotFound, do(2, http.MethodGet, path, nil).Code)
+
+	rec := do(1, http.MethodGet, path+"?limit=2", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page := &store.MessagePage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Messages, 2) {
+		assert.Equal(t, "how are you?", page.Messages[0].Body)
+		assert.Equal(t, users[1].ID, page.Messages[1].Sender)
+	}
+
+	rec = do(1, http.MethodGet, path+"?cursor="+page.NextCursor, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page = &store.MessagePage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if !assert.Len(t, page.Messages, 1) {
+		return
+	}
+	first := page.Messages[0]
+	messagePath := fmt.Sprintf("%s/%d", path, first.ID)
+
+	assert.Equal(t, http.StatusNotFound, do(1, http.MethodPatch, messagePath, messageRequest{Body: "bye"}).Code)
+
+	rec = do(0, http.MethodPatch, messagePath, messageRequest{Body: "hey"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	m := &model.Message{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(m))
+	assert.Equal(t, "hey", m.Body)
+	assert.True(t, m.Edited)
+
+	assert.Equal(t, http.StatusOK, do(0, http.MethodDelete, messagePath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, do(0, http.MethodDelete, messagePath, nil).Code)
+
+	assert.Equal(t, http.StatusOK, do(1, http.MethodDelete, fmt.Sprintf("/matches/%d", users[0].ID), nil).Code)
+
+	rec = do(0, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page = &store.MessagePage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	if assert.Len(t, page.Messages, 3) {
+		assert.True(t, page.Messages[2].Deleted)
+		assert.Empty(t, page.Messages[2].Body)
+	}
+
+	rec = do(0, http.MethodPost, path, messageRequest{Body: "wait"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	e := &encd_err{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(e))
+	assert.Equal(t, codeThreadLocked, e.Code)
+}
+
+func TestServer_HandlerWS(t *testing.T) {
+	s, st := testServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	cookies := make([]*http.Cookie, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s, u)
+	}
+
+	do := func(i int, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		json.NewEncoder(b).Encode(payload)
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookies[i])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	dial := func(i int) *websocket.Conn {
+		header := http.Header{}
+		header.Add("Cookie", cookies[i].String())
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Events are only delivered once the connection is registered.
+		assert.Eventually(t, func() bool {
+			s.hub.mu.Lock()
+			defer s.hub.mu.Unlock()
+			return len(s.hub.clients[users[i].ID]) == 1
+		}, time.Second, time.Millisecond)
+
+		return conn
+	}
+
+	read := func(conn *websocket.Conn, eventType string, data interface{}) {
+		t.Helper()
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		e := &struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := conn.ReadJSON(e); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, eventType, e.Type)
+		assert.NoError(t, json.Unmarshal(e.Data, data))
+	}
+
+	conns := []*websocket.Conn{dial(0), dial(1), dial(2)}
+
+	assert.Equal(t, http.StatusCreated, do(1, http.MethodPost, "/likes", map[string]int{"liked_user": users[0].ID}).Code)
+	l := &model.Like{}
+	read(conns[0], eventLikeReceived, l)
+	assert.Equal(t, users[1].ID, l.UserID)
+
+	assert.Equal(t, http.StatusCreated, do(0, http.MethodPost, "/likes", map[string]int{"liked_user": users[1].ID}).Code)
+	read(conns[1], eventLikeReceived, l)
+	for i, conn := range conns[:2] {
+		m := &model.Match{}
+		read(conn, eventMatchCreated, m)
+		assert.Equal(t, users[i].ID, m.User1)
+		assert.Equal(t, users[1-i].ID, m.User2)
+	}
+
+	match, err := st.Match().FindByUsers(ctx, users[0].ID, users[1].ID)
+	assert.NoError(t, err)
+
+	rec := do(0, http.MethodPost, fmt.Sprintf("/matches/%d/messages", match.ID), messageRequest{Body: "hi"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	for _, conn := range conns[:2] {
+		m := &model.Message{}
+		read(conn, eventMessageCreated, m)
+		assert.Equal(t, "hi", m.Body)
+	}
+
+	assert.NoError(t, conns[1].WriteJSON(&frame{Type: eventTyping, MatchID: match.ID}))
+	signal := &threadSignal{}
+	read(conns[0], eventTyping, signal)
+	assert.Equal(t, users[1].ID, signal.UserID)
+
+	assert.NoError(t, conns[1].WriteJSON(&frame{Type: eventMessageRead, MatchID: match.ID, MessageID: 1}))
+	read(conns[0], eventMessageRead, signal)
+	assert.Equal(t, 1, signal.MessageID)
+
+	assert.NoError(t, conns[2].WriteJSON(&frame{Type: eventTyping, MatchID: match.ID}))
+	e := &encd_err{}
+	read(conns[2], eventError, e)
+	assert.Equal(t, codeNotFound, e.Code)
+
+	assert.NoError(t, conns[2].WriteJSON(&frame{Type: "shout", MatchID: match.ID}))
+	read(conns[2], eventError, e)
+	assert.Equal(t, codeBadRequest, e.Code)
+
+	s.hub.close()
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	h := newHub()
+
+	slow := newClient(nil, 1)
+	fast := newClient(nil, 1)
+	other := newClient(nil, 2)
+	for _, c := range []*client{slow, fast, other} {
+		assert.True(t, h.register(c))
+	}
+
+	for i := 0; i < sendBufferSize; i++ {
+		h.publish(&event{Type: eventTyping}, 1)
+		<-fast.send
+	}
+
+	select {
+	case <-slow.done:
+		t.Fatal("client with a full buffer is not slow yet")
+	default:
+	}
+
+	// One more event does not fit into the buffer of slow, which is dropped
+	// without holding up fast.
+	h.publish(&event{Type: eventTyping}, 1)
+	<-slow.done
+	assert.Equal(t, websocket.CloseTryAgainLater, slow.closeCode)
+	assert.Len(t, fast.send, 1)
+	assert.Empty(t, other.send)
+
+	h.unregister(slow)
+	h.unregister(fast)
+	h.unregister(other)
+	h.close()
+	assert.False(t, h.register(newClient(nil, 1)))
+}
+
+func TestServer_HandlerEvents(t *testing.T) {
+	s, st := testServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	ctx := context.Background()
+
+	users := make([]*model.User, 2)
+	cookies := make([]*http.Cookie, 2)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s, u)
+	}
+
+	do := func(i int, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		json.NewEncoder(b).Encode(payload)
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookies[i])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	type sse struct {
+		id        string
+		eventType string
+		data      string
+	}
+
+	stream := func(lastEventID string) (*bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+		req.AddCookie(cookies[0])
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		return bufio.NewReader(resp.Body), cancel
+	}
+
+	read := func(r *bufio.Reader) *sse {
+		t.Helper()
+
+		e := &sse{}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && e.eventType != "":
+				return e
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.eventType = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	// Events logged while the user was offline are replayed.
+	assert.Equal(t, http.StatusCreated, do(1, http.MethodPost, "/likes", map[string]int{"liked_user": users[0].ID}).Code)
+
+	r, cancel := stream("")
+	defer cancel()
+
+	liked := read(r)
+	assert.Equal(t, eventLikeReceived, liked.eventType)
+	l := &model.Like{}
+	assert.NoError(t, json.Unmarshal([]byte(liked.data), l))
+	assert.Equal(t, users[1].ID, l.UserID)
+
+	assert.Equal(t, http.StatusCreated, do(0, http.MethodPost, "/likes", map[string]int{"liked_user": users[1].ID}).Code)
+	matched := read(r)
+	assert.Equal(t, eventMatchCreated, matched.eventType)
+	m := &model.Match{}
+	assert.NoError(t, json.Unmarshal([]byte(matched.data), m))
+	assert.Equal(t, users[0].ID, m.User1)
+
+	assert.Equal(t, http.StatusOK, do(1, http.MethodDelete, "/likes", map[string]int{"liked_user": users[0].ID}).Code)
+	assert.Equal(t, eventMatchRemoved, read(r).eventType)
+	cancel()
+
+	// A reconnecting client gets only what follows Last-Event-ID.
+	r, cancel = stream(liked.id)
+	defer cancel()
+
+	resumed := read(r)
+	assert.Equal(t, matched.id, resumed.id)
+	assert.Equal(t, eventMatchRemoved, read(r).eventType)
+
+	// Pushes of concurrent transactions may come out of order, but the
+	// stream reads the log and skips none of the events.
+	early := &model.Event{UserID: users[0].ID, Type: eventLikeReceived, Data: json.RawMessage(`{}`)}
+	assert.NoError(t, st.Event().Create(ctx, early))
+	late := &model.Event{UserID: users[0].ID, Type: eventLikeReceived, Data: json.RawMessage(`{}`)}
+	assert.NoError(t, st.Event().Create(ctx, late))
+
+	s.push(ctx, pendingEvents{late})
+	assert.Equal(t, fmt.Sprint(early.ID), read(r).id)
+	assert.Equal(t, fmt.Sprint(late.ID), read(r).id)
+	s.push(ctx, pendingEvents{early})
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.AddCookie(cookies[0])
+	req.Header.Set("Last-Event-ID", "latest")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_PubSub(t *testing.T) {
+	// Two instances of the API share the store and the pubsub.
+	s1, st := testServer(t)
+	s2 := newServer(st, teststore.NewPhotoStore(), sessions.NewCookieStore([]byte("secret")))
+	ps := pubsub.NewMemory()
+	s1.usePubSub(ps)
+	s2.usePubSub(ps)
+
+	ts := httptest.NewServer(s2)
+	defer ts.Close()
+	ctx := context.Background()
+
+	users := make([]*model.User, 2)
+	cookies := make([]*http.Cookie, 2)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s1, u)
+	}
+
+	header := http.Header{}
+	header.Add("Cookie", cookies[0].String())
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool {
+		s2.hub.mu.Lock()
+		defer s2.hub.mu.Unlock()
+		return len(s2.hub.clients[users[0].ID]) == 1
+	}, time.Second, time.Millisecond)
+
+	// The like is created on the first instance and reaches the user
+	// connected to the second one.
+	b := &bytes.Buffer{}
+	json.NewEncoder(b).Encode(map[string]int{"liked_user": users[0].ID})
+	req := httptest.NewRequest(http.MethodPost, "/likes", b)
+	req.AddCookie(cookies[1])
+	rec := httptest.NewRecorder()
+	s1.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	e := &delivery{}
+	assert.NoError(t, conn.ReadJSON(e))
+	assert.Equal(t, eventLikeReceived, e.Type)
+	assert.NotZero(t, e.ID)
+
+	l := &model.Like{}
+	assert.NoError(t, json.Unmarshal(e.Data, l))
+	assert.Equal(t, users[1].ID, l.UserID)
+}
+
+func TestServer_Outbox(t *testing.T) {
+	s, st := testServer(t)
+	ctx := context.Background()
+
+	d := s.dispatcher
+	got := make([]*model.OutboxMessage, 0)
+	for _, eventType := range model.DomainEventTypes {
+		d.Subscribe(eventType, func(ctx context.Context, m *model.OutboxMessage) error {
+			got = append(got, m)
+			return nil
+		})
+	}
+
+	types := func() []string {
+		got = got[:0]
+		_, err := d.Dispatch(ctx)
+		assert.NoError(t, err)
+
+		types := make([]string, 0, len(got))
+		for _, m := range got {
+			types = append(types, m.Type)
+		}
+		return types
+	}
+
+	b := &bytes.Buffer{}
+	mw := multipart.NewWriter(b)
+	up, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	json.NewEncoder(up).Encode(testUser(t))
+	pp, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"image/jpeg"}})
+	pp.Write(jpegHeader)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", b)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	assert.Equal(t, []string{model.DomainUserRegistered}, types())
+	registered := &model.User{}
+	assert.NoError(t, json.Unmarshal(got[0].Payload, registered))
+	assert.NotZero(t, registered.ID)
+	assert.Empty(t, registered.Password)
+
+	users := make([]*model.User, 2)
+	cookies := make([]*http.Cookie, 2)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, st.User().Create(ctx, u))
+		u.Password = testUser(t).Password
+		users[i] = u
+		cookies[i] = login(t, s, u)
+	}
+
+	do := func(i int, method, path string, payload interface{}) int {
+		b := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(b).Encode(payload)
+		}
+
+		req := httptest.NewRequest(method, path, b)
+		req.AddCookie(cookies[i])
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, do(0, http.MethodPost, "/likes", map[string]int{"liked_user": users[1].ID}))
+	assert.Equal(t, http.StatusCreated, do(1, http.MethodPost, "/likes", map[string]int{"liked_user": users[0].ID}))
+	// A failed request writes nothing.
+	assert.NotEqual(t, http.StatusCreated, do(1, http.MethodPost, "/likes", map[string]int{"liked_user": users[0].ID}))
+	assert.Equal(t, []string{model.DomainLikeCreated, model.DomainLikeCreated, model.DomainMatchCreated}, types())
+
+	assert.Equal(t, http.StatusOK, do(1, http.MethodDelete, "/users/current", nil))
+	assert.Equal(t, []string{model.DomainMatchRemoved, model.DomainUserDeleted}, types())
+	deleted := &model.DeletedUser{}
+	assert.NoError(t, json.Unmarshal(got[1].Payload, deleted))
+	assert.Equal(t, users[1].ID, deleted.UserID)
+
+	assert.Empty(t, types())
+
+	// Every event was handled by the subscribers of the server too.
+	for _, status := range []string{model.OutboxPending, model.OutboxDead} {
+		left, err := st.Outbox().FindByStatus(ctx, status, 0, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, left)
+	}
+}
+
+func TestServer_AdminOutbox(t *testing.T) {
+	s, st := testServer(t)
+	s.adminToken = "admin_token"
+	ctx := context.Background()
+
+	m := &model.OutboxMessage{Type: model.DomainUserDeleted, Payload: json.RawMessage(`{"user_id":1}`)}
+	assert.NoError(t, st.Outbox().Create(ctx, m))
+	m.Status = model.OutboxDead
+	m.Attempts = 10
+	m.LastError = "unavailable"
+	assert.NoError(t, st.Outbox().Update(ctx, m))
+
+	do := func(token, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	deadPage := func(query string) *store.OutboxPage {
+		rec := do("admin_token", http.MethodGet, "/admin/outbox/dead"+query)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		page := &store.OutboxPage{}
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+		return page
+	}
+
+	dead := func() []*model.OutboxMessage {
+		return deadPage("").Messages
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("wrong", http.MethodGet, "/admin/outbox/dead").Code)
+	assert.Equal(t, http.StatusUnauthorized, do("wrong", http.MethodPost, fmt.Sprintf("/admin/outbox/%d/replay", m.ID)).Code)
+
+	if messages := dead(); assert.Len(t, messages, 1) {
+		assert.Equal(t, m.ID, messages[0].ID)
+		assert.Equal(t, "unavailable", messages[0].LastError)
+	}
+
+	rec := do("admin_token", http.MethodPost, fmt.Sprintf("/admin/outbox/%d/replay", m.ID))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	replayed := &model.OutboxMessage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(replayed))
+	assert.Equal(t, model.OutboxPending, replayed.Status)
+	assert.Zero(t, replayed.Attempts)
+	assert.Empty(t, dead())
+
+	// Only dead messages are replayed.
+	assert.Equal(t, http.StatusNotFound, do("admin_token", http.MethodPost, fmt.Sprintf("/admin/outbox/%d/replay", m.ID)).Code)
+
+	// The dispatcher delivers the replayed message to the subscribers.
+	n, err := s.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.ErrorIs(t, st.Outbox().DeleteByID(ctx, m.ID), store.ErrNotFound)
+
+	// Dead messages are paged by the cursor.
+	ids := make([]int, 0)
+	for i := 0; i < 3; i++ {
+		m := &model.OutboxMessage{Type: model.DomainUserDeleted, Payload: json.RawMessage(`{"user_id":1}`)}
+		assert.NoError(t, st.Outbox().Create(ctx, m))
+		m.Status = model.OutboxDead
+		assert.NoError(t, st.Outbox().Update(ctx, m))
+		ids = append(ids, m.ID)
+	}
+
+	got := make([]int, 0)
+	page := deadPage("?limit=2")
+	for {
+		for _, m := range page.Messages {
+			got = append(got, m.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page = deadPage("?limit=2&cursor=" + page.NextCursor)
+	}
+	assert.Equal(t, ids, got)
+
+	rec = do("admin_token", http.MethodGet, "/admin/outbox/dead?cursor=bad")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,243 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+const (
+	// writeWait bounds writing one frame to a client.
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent before it is dropped.
+	pongWait = 60 * time.Second
+	// pingPeriod is shorter than pongWait, so pings keep healthy clients
+	// from being dropped.
+	pingPeriod = pongWait * 9 / 10
+	// maxFrameSize limits the frames clients send.
+	maxFrameSize = 4096
+	// sendBufferSize is how many events may wait for a client before it
+	// is considered too slow.
+	sendBufferSize = 64
+)
+
+var errUnknownFrame = errors.New("unknown frame type")
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// frame is what clients send: typing indicators and read receipts for the
+// thread of a match.
+type frame struct {
+	Type      string `json:"type"`
+	MatchID   int    `json:"match_id"`
+	MessageID int    `json:"message_id,omitempty"`
+}
+
+// threadSignal is a typing indicator or a read receipt of UserID.
+type threadSignal struct {
+	MatchID   int `json:"match_id"`
+	UserID    int `json:"user_id"`
+	MessageID int `json:"message_id,omitempty"`
+}
+
+// client is one realtime connection of a user.
+type client struct {
+	conn   *websocket.Conn
+	userID int
+	send   chan *event
+
+	once      sync.Once
+	done      chan struct{}
+	closeCode int
+	closeText string
+}
+
+func newClient(conn *websocket.Conn, userID int) *client {
+	return &client{
+		conn:   conn,
+		userID: userID,
+		send:   make(chan *event, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// stop makes the write pump close the connection with code. Only the first
+// call has an effect.
+func (c *client) stop(code int, text string) {
+	c.once.Do(func() {
+		c.closeCode, c.closeText = code, text
+		close(c.done)
+	})
+}
+
+// writePump is the only writer of the connection: it sends queued events and
+// pings until the client is stopped or a write fails.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case e := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// handlerWS upgrades the request to a websocket that receives the events of
+// the current user and accepts frames from it.
+func (s *server) handlerWS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Println("Processing by handlerWS()")
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// The upgrader has already answered the request.
+			s.err_logger.Println(requestID(r), "Cannot upgrade connection:", err)
+			return
+		}
+
+		c := newClient(conn, r.Context().Value(ctxUserKey).(*model.User).ID)
+		if !s.hub.register(c) {
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			conn.Close()
+			return
+		}
+
+		go c.writePump()
+		s.readPump(r.Context(), c)
+	}
+}
+
+// readPump handles the frames of c until the connection fails or closes.
+func (s *server) readPump(ctx context.Context, c *client) {
+	defer func() {
+		s.hub.unregister(c)
+		c.stop(websocket.CloseNormalClosure, "")
+	}()
+
+	c.conn.SetReadLimit(maxFrameSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		f := &frame{}
+		if err := json.Unmarshal(b, f); err != nil {
+			s.replyError(c, codeInvalidJSON, err.Error())
+			continue
+		}
+
+		if err := s.handleFrame(ctx, c, f); err != nil {
+			code, message := s.frameError(err)
+			s.replyError(c, code, message)
+		}
+	}
+}
+
+// handleFrame forwards a typing indicator or a read receipt to the other
+// user of an open thread.
+func (s *server) handleFrame(ctx context.Context, c *client, f *frame) error {
+	var eventType string
+	switch f.Type {
+	case eventTyping:
+		eventType = eventTyping
+	case eventMessageRead:
+		eventType = eventMessageRead
+	default:
+		return errUnknownFrame
+	}
+
+	t, err := s.store.Message().FindThread(ctx, f.MatchID)
+	if err != nil {
+		return err
+	}
+
+	if !t.Has(c.userID) {
+		return store.ErrNotFound
+	}
+
+	if t.Locked {
+		return store.ErrThreadLocked
+	}
+
+	other := t.Other(c.userID)
+	blocked, err := s.store.Block().Exists(ctx, c.userID, other)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return store.ErrNotFound
+	}
+
+	s.publish(ctx, &event{
+		Type: eventType,
+		Data: &threadSignal{MatchID: t.MatchID, UserID: c.userID, MessageID: f.MessageID},
+	}, other)
+
+	return nil
+}
+
+// frameError returns the code and the message of err the REST API would
+// answer with.
+func (s *server) frameError(err error) (string, string) {
+	for _, m := range storeErrors {
+		if errors.Is(err, m.err) {
+			return m.code, m.err.Error()
+		}
+	}
+
+	if err == errUnknownFrame {
+		return codeBadRequest, err.Error()
+	}
+
+	s.err_logger.Println("Cannot handle frame:", err.Error())
+	return codeInternal, errInternal.Error()
+}
+
+// replyError pushes an error event to c alone.
+func (s *server) replyError(c *client, code, message string) {
+	e := &event{
+		Type: eventError,
+		Data: &encd_err{Code: code, Message: message},
+	}
+
+	select {
+	case c.send <- e:
+	default:
+		c.stop(websocket.CloseTryAgainLater, "client is too slow")
+	}
+}
@@ -0,0 +1,78 @@
+package migrator_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kek-flip/scotch-api/internal/migrator"
+	"github.com/kek-flip/scotch-api/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		isValid  bool
+	}{
+		{
+			name: "valid",
+			fsys: fstest.MapFS{
+				"2_second.up.sql":   {Data: []byte("up 2")},
+				"2_second.down.sql": {Data: []byte("down 2")},
+				"1_first.up.sql":    {Data: []byte("up 1")},
+				"1_first.down.sql":  {Data: []byte("down 1")},
+				"README.md":         {Data: []byte("not a migration")},
+			},
+			versions: []int64{1, 2},
+			isValid:  true,
+		},
+		{
+			name: "empty down",
+			fsys: fstest.MapFS{
+				"1_first.up.sql":   {Data: []byte("up 1")},
+				"1_first.down.sql": {Data: []byte("")},
+			},
+			isValid: false,
+		},
+		{
+			name: "no up",
+			fsys: fstest.MapFS{
+				"1_first.down.sql": {Data: []byte("down 1")},
+			},
+			isValid: false,
+		},
+		{
+			name: "names differ",
+			fsys: fstest.MapFS{
+				"1_first.up.sql":   {Data: []byte("up 1")},
+				"1_other.down.sql": {Data: []byte("down 1")},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := migrator.Load(tc.fsys)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			versions := make([]int64, 0)
+			for _, m := range ms {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tc.versions, versions)
+		})
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	ms, err := migrator.Load(migrations.FS)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ms)
+}
@@ -0,0 +1,301 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	versionTable = "schema_versions"
+	// legacyTable is where the golang-migrate CLI keeps its single version.
+	legacyTable = "schema_migrations"
+	// lockID keys the advisory lock that keeps concurrent runners apart.
+	lockID = 7_420_230_209
+)
+
+var (
+	ErrNoSuchVersion = errors.New("no migration with this version")
+	ErrDirtyLegacy   = errors.New("schema_migrations is dirty, fix the database by hand first")
+)
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []*Migration
+}
+
+func New(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns the version of the newest known migration or 0 if there are none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		at, ok := applied[mg.Version]
+		statuses = append(statuses, &Status{
+			Version:   mg.Version,
+			Name:      mg.Name,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet. It never changes
+// the database, so it is safe to call with an unprivileged role.
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]*Migration, 0)
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; !ok {
+			pending = append(pending, mg)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, mg); err != nil {
+				return err
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Goto applies or reverts migrations until exactly the ones with versions
+// up to and including version are applied. Version 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return ErrNoSuchVersion
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok || mg.Version <= version {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, mg); err != nil {
+				return err
+			}
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok || mg.Version > version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mg *Migration) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mg.Up); err != nil {
+			return fmt.Errorf("apply %d_%s: %w", mg.Version, mg.Name, err)
+		}
+
+		_, err := tx.Exec(ctx, "INSERT INTO "+versionTable+"(version) VALUES ($1)", mg.Version)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *pgxpool.Conn, mg *Migration) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mg.Down); err != nil {
+			return fmt.Errorf("revert %d_%s: %w", mg.Version, mg.Name, err)
+		}
+
+		_, err := tx.Exec(ctx, "DELETE FROM "+versionTable+" WHERE version = $1", mg.Version)
+		return err
+	})
+}
+
+// withLock runs fn on a single connection holding the migration lock, with
+// the version table created and any golang-migrate history adopted.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]time.Time) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	_, err = conn.Exec(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS `+versionTable+` (
+			version BIGINT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	// Persist versions that so far were only known from the legacy table.
+	for version := range applied {
+		_, err := conn.Exec(
+			ctx,
+			"INSERT INTO "+versionTable+"(version) VALUES ($1) ON CONFLICT DO NOTHING",
+			version,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return fn(conn, applied)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func tableExists(ctx context.Context, q querier, name string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists)
+	return exists, err
+}
+
+// applied returns the applied versions with the time they were applied at.
+// Databases migrated by the golang-migrate CLI only have its single version
+// row, so every known migration up to it counts as applied.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	applied := make(map[int64]time.Time)
+
+	exists, err := tableExists(ctx, q, versionTable)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		rows, err := q.Query(ctx, "SELECT version, applied_at FROM "+versionTable)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var version int64
+			var at time.Time
+			if err := rows.Scan(&version, &at); err != nil {
+				return nil, err
+			}
+			applied[version] = at
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(applied) != 0 {
+		return applied, nil
+	}
+
+	exists, err = tableExists(ctx, q, legacyTable)
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	var version int64
+	var dirty bool
+	err = q.QueryRow(ctx, "SELECT version, dirty FROM "+legacyTable+" LIMIT 1").Scan(&version, &dirty)
+	if err == pgx.ErrNoRows {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, ErrDirtyLegacy
+	}
+
+	for _, mg := range m.migrations {
+		if mg.Version <= version {
+			applied[mg.Version] = time.Time{}
+		}
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Block hides UserID and BlockedUser from each other.
+type Block struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	BlockedUser int       `json:"blocked_user"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (b *Block) Validate() error {
+	err := validation.ValidateStruct(
+		b,
+		validation.Field(&b.UserID, validation.Required, validation.Min(1)),
+		validation.Field(&b.BlockedUser, validation.Required, validation.Min(1)),
+		validation.Field(&b.Reason, validation.Length(0, 255)),
+	)
+
+	if b.UserID == b.BlockedUser {
+		err = errors.New("user_id cannot equal blocked_user")
+	}
+
+	return err
+}
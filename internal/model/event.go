@@ -0,0 +1,23 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the events kept in the event log.
+const (
+	EventLikeReceived = "like.received"
+	EventMatchCreated = "match.created"
+	EventMatchRemoved = "match.removed"
+)
+
+// Event is a change that concerns UserID. Events are logged, so that clients
+// that were offline can catch up on them in the order of their ids.
+type Event struct {
+	ID        int             `json:"id"`
+	UserID    int             `json:"user_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
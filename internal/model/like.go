@@ -2,14 +2,28 @@ package model
 
 import (
 	"errors"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
+const (
+	LikeTypeRegular = "regular"
+	LikeTypeSuper   = "super"
+)
+
+// SuperLikeCounter names the counters of super likes sent in a day. Deleting
+// a super like leaves its counter as is, so that the quota is not given back.
+const SuperLikeCounter = "super_like"
+
+// Like is a swipe that shows interest in a profile. An empty Type is stored
+// as a regular like.
 type Like struct {
-	ID        int `json:"id"`
-	UserID    int `json:"user_id"`
-	LikedUser int `json:"liked_user"`
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	LikedUser int       `json:"liked_user"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func (l *Like) Validate() error {
@@ -17,6 +31,7 @@ func (l *Like) Validate() error {
 		l,
 		validation.Field(&l.UserID, validation.Required, validation.Min(1)),
 		validation.Field(&l.LikedUser, validation.Required, validation.Min(1)),
+		validation.Field(&l.Type, validation.In(LikeTypeRegular, LikeTypeSuper)),
 	)
 
 	if l.UserID == l.LikedUser {
@@ -25,3 +40,13 @@ func (l *Like) Validate() error {
 
 	return err
 }
+
+// IsSuper reports whether l is a super like.
+func (l *Like) IsSuper() bool {
+	return l.Type == LikeTypeSuper
+}
+
+// SuperLikeLimit is the daily quota of max super likes.
+func SuperLikeLimit(max int) *Limit {
+	return &Limit{Name: SuperLikeCounter, Max: max}
+}
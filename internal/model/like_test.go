@@ -30,6 +30,24 @@ func TestLike_Validation(t *testing.T) {
 			},
 			isValid: true,
 		},
+		{
+			name: "Super like",
+			l: func() *model.Like {
+				l := testLike(t)
+				l.Type = model.LikeTypeSuper
+				return l
+			},
+			isValid: true,
+		},
+		{
+			name: "Unknown type",
+			l: func() *model.Like {
+				l := testLike(t)
+				l.Type = "mega"
+				return l
+			},
+			isValid: false,
+		},
 		{
 			name: "Same users",
 			l: func() *model.Like {
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// Limit allows a user at most Max actions of a kind in every window of
+// Period. A zero Period makes the window the calendar day of the user.
+type Limit struct {
+	Name   string
+	Max    int
+	Period time.Duration
+}
+
+// minDayWindow is how long a daily window lasts at least. A user who moves
+// their midnight by changing the timezone gets their next window no sooner.
+const minDayWindow = 20 * time.Hour
+
+// Window returns the bounds of the window of l that contains t for a user
+// in the timezone tz whose last window is prev, which may be nil.
+//
+// A daily window runs until its end even if the user changes the timezone
+// meanwhile, and the next one starts no earlier and lasts at least
+// minDayWindow, so that the timezone cannot be used to reset the limit.
+func (l *Limit) Window(t time.Time, tz string, prev *Counter) (time.Time, time.Time) {
+	if l.Period > 0 {
+		start := t.Truncate(l.Period)
+		return start, start.Add(l.Period)
+	}
+
+	if prev != nil && !t.Before(prev.WindowStart) && t.Before(prev.WindowEnd) {
+		return prev.WindowStart, prev.WindowEnd
+	}
+
+	start, end := LocalDay(t, tz)
+	if prev != nil && start.Before(prev.WindowEnd) {
+		start = prev.WindowEnd
+		if end.Sub(start) < minDayWindow {
+			_, end = LocalDay(start.Add(minDayWindow), tz)
+		}
+	}
+
+	return start, end
+}
+
+// Counter returns the counter of u for the window of l containing t, given
+// prev, the last counter of u for l or nil. Count is that of prev if t falls
+// into its window and zero otherwise.
+func (l *Limit) Counter(u *User, prev *Counter, t time.Time) *Counter {
+	start, end := l.Window(t, u.Timezone, prev)
+
+	c := &Counter{
+		UserID:      u.ID,
+		Name:        l.Name,
+		WindowStart: start,
+		WindowEnd:   end,
+	}
+	if prev != nil && prev.WindowStart.Equal(start) {
+		c.Count = prev.Count
+	}
+
+	return c
+}
+
+// Counter is how many actions counted against a limit a user made in one
+// window of it.
+type Counter struct {
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Count       int       `json:"count"`
+}
@@ -0,0 +1,45 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimit_Window(t *testing.T) {
+	now := time.Date(2023, 3, 10, 22, 30, 15, 0, time.UTC)
+
+	l := &model.Limit{Name: "burst", Max: 10, Period: time.Minute}
+	start, end := l.Window(now, "Europe/Moscow", nil)
+	assert.Equal(t, time.Date(2023, 3, 10, 22, 30, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2023, 3, 10, 22, 31, 0, 0, time.UTC), end)
+
+	l = &model.Limit{Name: "daily", Max: 100}
+	start, end = l.Window(now, "Europe/Moscow", nil)
+	assert.Equal(t, time.Date(2023, 3, 10, 21, 0, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, time.Date(2023, 3, 11, 21, 0, 0, 0, time.UTC), end.UTC())
+}
+
+func TestLimit_WindowTimezoneChange(t *testing.T) {
+	l := &model.Limit{Name: "daily", Max: 100}
+	now := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	start, end := l.Window(now, "UTC", nil)
+	prev := &model.Counter{Name: l.Name, WindowStart: start, WindowEnd: end, Count: 100}
+
+	// Moving the midnight does not start a new window before this one ends.
+	c := l.Counter(&model.User{Timezone: "Pacific/Kiritimati"}, prev, now)
+	assert.Equal(t, start, c.WindowStart)
+	assert.Equal(t, 100, c.Count)
+
+	// The next window starts when the last one ended and runs at least
+	// until the first midnight 20 hours later.
+	next := l.Counter(&model.User{Timezone: "Pacific/Kiritimati"}, prev, end.Add(time.Minute))
+	assert.Equal(t, end, next.WindowStart)
+	assert.Zero(t, next.Count)
+	assert.GreaterOrEqual(t, next.WindowEnd.Sub(next.WindowStart), 20*time.Hour)
+	_, localEnd := model.LocalDay(next.WindowEnd.Add(-time.Nanosecond), "Pacific/Kiritimati")
+	assert.Equal(t, localEnd, next.WindowEnd)
+}
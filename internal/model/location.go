@@ -0,0 +1,60 @@
+package model
+
+import (
+	"math"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// EarthRadius is the mean radius of the Earth in kilometers.
+const EarthRadius = 6371.0
+
+// LocationGrid is the side in degrees of the cells locations are snapped to
+// before the distance between users is computed, about 2 km of latitude.
+const LocationGrid = 0.02
+
+// Location is a point on the Earth in degrees.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (l *Location) Validate() error {
+	return validation.ValidateStruct(
+		l,
+		validation.Field(&l.Latitude, validation.Min(-90.0), validation.Max(90.0)),
+		validation.Field(&l.Longitude, validation.Min(-180.0), validation.Max(180.0)),
+	)
+}
+
+// DistanceTo returns the great-circle distance to o in kilometers.
+func (l *Location) DistanceTo(o *Location) float64 {
+	lat1, lat2 := radians(l.Latitude), radians(o.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(o.Longitude - l.Longitude)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Snap returns the center of the grid cell of l. Positions within a cell are
+// told apart by nothing computed from snapped locations, so moving one's own
+// location around does not pin down where someone else is.
+func (l *Location) Snap() *Location {
+	return &Location{Latitude: snap(l.Latitude), Longitude: snap(l.Longitude)}
+}
+
+// ApproxDistanceTo returns the distance between the snapped locations rounded
+// up to whole kilometers.
+func (l *Location) ApproxDistanceTo(o *Location) int {
+	return int(math.Max(1, math.Ceil(l.Snap().DistanceTo(o.Snap()))))
+}
+
+func snap(deg float64) float64 {
+	return (math.Floor(deg/LocationGrid) + 0.5) * LocationGrid
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
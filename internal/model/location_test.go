@@ -0,0 +1,63 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocation_Validation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		l       *model.Location
+		isValid bool
+	}{
+		{
+			name:    "Valid location",
+			l:       &model.Location{Latitude: 55.75, Longitude: 37.62},
+			isValid: true,
+		},
+		{
+			name:    "Latitude out of range",
+			l:       &model.Location{Latitude: 91, Longitude: 37.62},
+			isValid: false,
+		},
+		{
+			name:    "Longitude out of range",
+			l:       &model.Location{Latitude: 55.75, Longitude: -181},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.l.Validate())
+			} else {
+				assert.Error(t, tc.l.Validate())
+			}
+		})
+	}
+}
+
+func TestLocation_DistanceTo(t *testing.T) {
+	moscow := &model.Location{Latitude: 55.7558, Longitude: 37.6173}
+	petersburg := &model.Location{Latitude: 59.9311, Longitude: 30.3609}
+
+	assert.InDelta(t, 634, moscow.DistanceTo(petersburg), 5)
+	assert.InDelta(t, moscow.DistanceTo(petersburg), petersburg.DistanceTo(moscow), 1e-9)
+	assert.Equal(t, 0.0, moscow.DistanceTo(moscow))
+	assert.Equal(t, 1, moscow.ApproxDistanceTo(moscow))
+}
+
+func TestLocation_ApproxDistanceTo(t *testing.T) {
+	moscow := &model.Location{Latitude: 55.7558, Longitude: 37.6173}
+
+	// Positions within one cell of the grid cannot be told apart.
+	near := &model.Location{Latitude: 55.7801, Longitude: 37.6201}
+	nearby := &model.Location{Latitude: 55.7999, Longitude: 37.6399}
+	assert.Equal(t, near.Snap(), nearby.Snap())
+	assert.Equal(t, moscow.ApproxDistanceTo(near), moscow.ApproxDistanceTo(nearby))
+	assert.NotEqual(t, moscow.DistanceTo(near), moscow.DistanceTo(nearby))
+}
@@ -2,14 +2,16 @@ package model
 
 import (
 	"errors"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
 type Match struct {
-	ID    int `json:"id,omitempty"`
-	User1 int `json:"user_1"`
-	User2 int `json:"user_2"`
+	ID        int       `json:"id,omitempty"`
+	User1     int       `json:"user_1"`
+	User2     int       `json:"user_2"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func (m *Match) Validate() error {
@@ -25,3 +27,12 @@ func (m *Match) Validate() error {
 
 	return err
 }
+
+// Pair returns the matched users in the order they are stored in:
+// the smaller id first, so a pair has exactly one representation.
+func (m *Match) Pair() (int, int) {
+	if m.User1 > m.User2 {
+		return m.User2, m.User1
+	}
+	return m.User1, m.User2
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// MaxMessageLength is the longest message body, in characters.
+const MaxMessageLength = 4000
+
+// Message is sent by Sender to Recipient within the conversation of a match.
+// A deleted message keeps its place in the thread but loses its body.
+type Message struct {
+	ID        int       `json:"id"`
+	MatchID   int       `json:"match_id"`
+	Sender    int       `json:"sender"`
+	Recipient int       `json:"recipient"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Edited    bool      `json:"edited"`
+	Deleted   bool      `json:"deleted"`
+}
+
+func (m *Message) Validate() error {
+	return validation.ValidateStruct(
+		m,
+		validation.Field(&m.MatchID, validation.Required, validation.Min(1)),
+		validation.Field(&m.Sender, validation.Required, validation.Min(1)),
+		validation.Field(&m.Body, validation.Required, validation.RuneLength(1, MaxMessageLength)),
+	)
+}
+
+// Thread is the conversation of the match of User1 and User2. It outlives the
+// match: once the users unmatch the thread stays readable but is Locked.
+type Thread struct {
+	MatchID int  `json:"match_id"`
+	User1   int  `json:"user_1"`
+	User2   int  `json:"user_2"`
+	Locked  bool `json:"locked"`
+}
+
+// Has reports whether userID is one of the users of t.
+func (t *Thread) Has(userID int) bool {
+	return t.User1 == userID || t.User2 == userID
+}
+
+// Other returns the user of t who is not userID.
+func (t *Thread) Other(userID int) int {
+	if t.User1 == userID {
+		return t.User2
+	}
+	return t.User1
+}
@@ -0,0 +1,53 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		m       *model.Message
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			m:       &model.Message{MatchID: 1, Sender: 1, Body: "hi"},
+			isValid: true,
+		},
+		{
+			name:    "longest body",
+			m:       &model.Message{MatchID: 1, Sender: 1, Body: strings.Repeat("я", model.MaxMessageLength)},
+			isValid: true,
+		},
+		{
+			name:    "empty body",
+			m:       &model.Message{MatchID: 1, Sender: 1},
+			isValid: false,
+		},
+		{
+			name:    "too long body",
+			m:       &model.Message{MatchID: 1, Sender: 1, Body: strings.Repeat("a", model.MaxMessageLength+1)},
+			isValid: false,
+		},
+		{
+			name:    "no match",
+			m:       &model.Message{Sender: 1, Body: "hi"},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.m.Validate())
+			} else {
+				assert.Error(t, tc.m.Validate())
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the domain events written to the outbox.
+const (
+	DomainUserRegistered = "UserRegistered"
+	DomainLikeCreated    = "LikeCreated"
+	DomainMatchCreated   = "MatchCreated"
+	DomainMatchRemoved   = "MatchRemoved"
+	DomainUserDeleted    = "UserDeleted"
+)
+
+// DomainEventTypes are all the types of the domain events.
+var DomainEventTypes = []string{
+	DomainUserRegistered,
+	DomainLikeCreated,
+	DomainMatchCreated,
+	DomainMatchRemoved,
+	DomainUserDeleted,
+}
+
+// States of an outbox message. Delivered messages are removed, so a message
+// is either waiting for its next attempt or dead after the last one failed.
+const (
+	OutboxPending = "pending"
+	OutboxDead    = "dead"
+)
+
+// OutboxMessage is a domain event written in the same transaction as the
+// change it describes and kept until the subscribers have handled it.
+type OutboxMessage struct {
+	ID            int             `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// DeletedUser is the payload of DomainUserDeleted.
+type DeletedUser struct {
+	UserID int `json:"user_id"`
+}
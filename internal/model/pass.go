@@ -0,0 +1,30 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Pass is a swipe that turns a profile down.
+type Pass struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	PassedUser int       `json:"passed_user"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (p *Pass) Validate() error {
+	err := validation.ValidateStruct(
+		p,
+		validation.Field(&p.UserID, validation.Required, validation.Min(1)),
+		validation.Field(&p.PassedUser, validation.Required, validation.Min(1)),
+	)
+
+	if p.UserID == p.PassedUser {
+		err = errors.New("user_id cannot equal passed_user")
+	}
+
+	return err
+}
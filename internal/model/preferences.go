@@ -0,0 +1,62 @@
+package model
+
+import (
+	"errors"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Preferences describe whom a user wants to see in discovery.
+// Zero values mean "any".
+type Preferences struct {
+	UserID      int      `json:"-"`
+	Genders     []string `json:"genders"`
+	MinAge      int      `json:"min_age"`
+	MaxAge      int      `json:"max_age"`
+	Cities      []string `json:"cities"`
+	MaxDistance int      `json:"max_distance"`
+	Mutual      bool     `json:"mutual"`
+}
+
+func (p *Preferences) Validate() error {
+	err := validation.ValidateStruct(
+		p,
+		validation.Field(&p.Genders, validation.Each(validation.In("male", "female"))),
+		validation.Field(&p.MinAge, validation.Min(18), validation.Max(99)),
+		validation.Field(&p.MaxAge, validation.Min(18), validation.Max(99)),
+		validation.Field(&p.Cities, validation.Each(validation.Required, validation.Length(1, 25))),
+		validation.Field(&p.MaxDistance, validation.Min(0), validation.Max(20000)),
+	)
+
+	if err == nil && p.MinAge != 0 && p.MaxAge != 0 && p.MinAge > p.MaxAge {
+		err = validation.Errors{"min_age": errors.New("cannot be greater than max_age")}
+	}
+
+	return err
+}
+
+// Accepts reports whether u fits p.
+func (p *Preferences) Accepts(u *User) bool {
+	if len(p.Genders) != 0 && !contains(p.Genders, u.Gender) {
+		return false
+	}
+	if p.MinAge != 0 && u.Age < p.MinAge {
+		return false
+	}
+	if p.MaxAge != 0 && u.Age > p.MaxAge {
+		return false
+	}
+	if len(p.Cities) != 0 && !contains(p.Cities, u.City) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPreferences(t *testing.T) *model.Preferences {
+	t.Helper()
+
+	return &model.Preferences{
+		Genders:     []string{"female"},
+		MinAge:      20,
+		MaxAge:      30,
+		Cities:      []string{"valid_city"},
+		MaxDistance: 50,
+	}
+}
+
+func TestPreferences_Validation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		p       func() *model.Preferences
+		isValid bool
+	}{
+		{
+			name: "Valid preferences",
+			p: func() *model.Preferences {
+				return testPreferences(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "Empty preferences",
+			p: func() *model.Preferences {
+				return &model.Preferences{}
+			},
+			isValid: true,
+		},
+		{
+			name: "Unknown gender",
+			p: func() *model.Preferences {
+				p := testPreferences(t)
+				p.Genders = append(p.Genders, "other")
+				return p
+			},
+			isValid: false,
+		},
+		{
+			name: "Too young",
+			p: func() *model.Preferences {
+				p := testPreferences(t)
+				p.MinAge = 17
+				return p
+			},
+			isValid: false,
+		},
+		{
+			name: "Inverted age range",
+			p: func() *model.Preferences {
+				p := testPreferences(t)
+				p.MinAge, p.MaxAge = p.MaxAge, p.MinAge
+				return p
+			},
+			isValid: false,
+		},
+		{
+			name: "Empty city",
+			p: func() *model.Preferences {
+				p := testPreferences(t)
+				p.Cities = []string{""}
+				return p
+			},
+			isValid: false,
+		},
+		{
+			name: "Negative distance",
+			p: func() *model.Preferences {
+				p := testPreferences(t)
+				p.MaxDistance = -1
+				return p
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.p().Validate())
+			} else {
+				assert.Error(t, tc.p().Validate())
+			}
+		})
+	}
+}
+
+func TestPreferences_Accepts(t *testing.T) {
+	p := testPreferences(t)
+
+	u := testUser(t)
+	u.Gender = "female"
+	assert.True(t, p.Accepts(u))
+
+	u.Age = 31
+	assert.False(t, p.Accepts(u))
+
+	assert.True(t, (&model.Preferences{}).Accepts(u))
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Quota is how many of a limited action a user has left until ResetsAt.
+type Quota struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+func NewQuota(limit, used int, resetsAt time.Time) *Quota {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &Quota{
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+		ResetsAt:  resetsAt,
+	}
+}
+
+// LocalDay returns the bounds of the day containing t in the timezone tz.
+// Unknown timezones are treated as UTC.
+func LocalDay(t time.Time, tz string) (time.Time, time.Time) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	return start, start.AddDate(0, 0, 1)
+}
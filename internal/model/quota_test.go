@@ -0,0 +1,30 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalDay(t *testing.T) {
+	// 22:30 UTC is already the next day in Moscow (UTC+3).
+	now := time.Date(2023, 3, 10, 22, 30, 0, 0, time.UTC)
+
+	start, end := model.LocalDay(now, "Europe/Moscow")
+	assert.Equal(t, time.Date(2023, 3, 10, 21, 0, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, time.Date(2023, 3, 11, 21, 0, 0, 0, time.UTC), end.UTC())
+
+	start, end = model.LocalDay(now, "")
+	assert.Equal(t, time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2023, 3, 11, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestNewQuota(t *testing.T) {
+	q := model.NewQuota(3, 1, time.Time{})
+	assert.Equal(t, 2, q.Remaining)
+
+	q = model.NewQuota(1, 2, time.Time{})
+	assert.Equal(t, 0, q.Remaining)
+}
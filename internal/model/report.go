@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonFake          = "fake"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonUnderage      = "underage"
+	ReportReasonOther         = "other"
+)
+
+// ReportStatusPending is the status of reports waiting for a moderator.
+const ReportStatusPending = "pending"
+
+// Report asks moderators to look at ReportedUser.
+type Report struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	ReportedUser int       `json:"reported_user"`
+	Reason       string    `json:"reason"`
+	Comment      string    `json:"comment,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (r *Report) Validate() error {
+	err := validation.ValidateStruct(
+		r,
+		validation.Field(&r.UserID, validation.Required, validation.Min(1)),
+		validation.Field(&r.ReportedUser, validation.Required, validation.Min(1)),
+		validation.Field(&r.Reason, validation.Required, validation.In(
+			ReportReasonSpam,
+			ReportReasonFake,
+			ReportReasonHarassment,
+			ReportReasonInappropriate,
+			ReportReasonUnderage,
+			ReportReasonOther,
+		)),
+		validation.Field(&r.Comment, validation.Length(0, 1000)),
+	)
+
+	if r.UserID == r.ReportedUser {
+		err = errors.New("user_id cannot equal reported_user")
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport(t *testing.T) *model.Report {
+	t.Helper()
+
+	return &model.Report{
+		UserID:       1,
+		ReportedUser: 2,
+		Reason:       model.ReportReasonSpam,
+	}
+}
+
+func TestReport_Validation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		r       func() *model.Report
+		isValid bool
+	}{
+		{
+			name: "Valid report",
+			r: func() *model.Report {
+				return testReport(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "Empty reason",
+			r: func() *model.Report {
+				r := testReport(t)
+				r.Reason = ""
+				return r
+			},
+			isValid: false,
+		},
+		{
+			name: "Unknown reason",
+			r: func() *model.Report {
+				r := testReport(t)
+				r.Reason = "boring"
+				return r
+			},
+			isValid: false,
+		},
+		{
+			name: "Too long comment",
+			r: func() *model.Report {
+				r := testReport(t)
+				r.Comment = strings.Repeat("a", 1001)
+				return r
+			},
+			isValid: false,
+		},
+		{
+			name: "Same users",
+			r: func() *model.Report {
+				r := testReport(t)
+				r.ReportedUser = r.UserID
+				return r
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.r().Validate())
+			} else {
+				assert.Error(t, tc.r().Validate())
+			}
+		})
+	}
+}
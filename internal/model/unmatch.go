@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Unmatch records that UserID dissolved the match with UnmatchedUser.
+type Unmatch struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	UnmatchedUser int       `json:"unmatched_user"`
+	Reason        string    `json:"reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (u *Unmatch) Validate() error {
+	return validation.ValidateStruct(
+		u,
+		validation.Field(&u.UserID, validation.Required, validation.Min(1)),
+		validation.Field(&u.UnmatchedUser, validation.Required, validation.Min(1)),
+		validation.Field(&u.Reason, validation.Length(0, 255)),
+	)
+}
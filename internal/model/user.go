@@ -1,23 +1,33 @@
 package model
 
 import (
+	"errors"
 	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID                int    `json:"id,omitempty"`
-	Login             string `json:"login"`
-	Password          string `json:"password,omitempty"`
-	EncryptedPassword string `json:"-"`
-	Name              string `json:"name"`
-	Age               int    `json:"age"`
-	Gender            string `json:"gender"`
-	City              string `json:"city"`
-	PhoneNumber       string `json:"phone_number"`
-	About             string `json:"about"`
+	ID                int       `json:"id,omitempty"`
+	Login             string    `json:"login"`
+	Password          string    `json:"password,omitempty"`
+	EncryptedPassword string    `json:"-"`
+	Name              string    `json:"name"`
+	Age               int       `json:"age"`
+	Gender            string    `json:"gender"`
+	City              string    `json:"city"`
+	PhoneNumber       string    `json:"phone_number"`
+	About             string    `json:"about"`
+	Interests         []string  `json:"interests"`
+	Timezone          string    `json:"timezone"`
+	LastSeenAt        time.Time `json:"-"`
+	// Location is never sent to clients; they get Distance instead.
+	Location *Location `json:"-"`
+	Distance int       `json:"distance,omitempty"`
+	// SuperLiked marks, in the liked_by list, the users who sent a super like.
+	SuperLiked bool `json:"super_liked,omitempty"`
 }
 
 func (u *User) Validate() error {
@@ -30,9 +40,39 @@ func (u *User) Validate() error {
 		validation.Field(&u.Gender, validation.Required, validation.In("male", "female")),
 		validation.Field(&u.City, validation.Required),
 		validation.Field(&u.PhoneNumber, validation.Required, validation.Match(regexp.MustCompile(`\A(\+7|8)9[0-9]{9}\z`))),
+		validation.Field(&u.Interests, interestsRules...),
+		validation.Field(&u.Timezone, timezoneRules...),
 	)
 }
 
+var (
+	interestsRules = []validation.Rule{validation.Length(0, 10), validation.Each(validation.Required, validation.Length(1, 25))}
+	timezoneRules  = []validation.Rule{validation.Length(0, 64), validation.By(isTimezone)}
+)
+
+// ValidateUpdate validates the fields of u that are checked on update, where
+// the password is not set.
+func (u *User) ValidateUpdate() error {
+	return validation.ValidateStruct(
+		u,
+		validation.Field(&u.Interests, interestsRules...),
+		validation.Field(&u.Timezone, timezoneRules...),
+	)
+}
+
+func isTimezone(value interface{}) error {
+	tz, _ := value.(string)
+	if tz == "" {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(tz); err != nil {
+		return errors.New("must be an IANA timezone")
+	}
+
+	return nil
+}
+
 func (u *User) ClearPassword() {
 	u.Password = ""
 }
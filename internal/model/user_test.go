@@ -154,6 +154,42 @@ func TestUser_Validation(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			name: "Too many interests",
+			u: func() *model.User {
+				u := testUser(t)
+				u.Interests = strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")
+				return u
+			},
+			isValid: false,
+		},
+		{
+			name: "Empty interest",
+			u: func() *model.User {
+				u := testUser(t)
+				u.Interests = []string{"music", ""}
+				return u
+			},
+			isValid: false,
+		},
+		{
+			name: "Timezone",
+			u: func() *model.User {
+				u := testUser(t)
+				u.Timezone = "Europe/Moscow"
+				return u
+			},
+			isValid: true,
+		},
+		{
+			name: "Unknown timezone",
+			u: func() *model.User {
+				u := testUser(t)
+				u.Timezone = "Mars/Olympus"
+				return u
+			},
+			isValid: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -167,6 +203,20 @@ func TestUser_Validation(t *testing.T) {
 	}
 }
 
+func TestUser_ValidateUpdate(t *testing.T) {
+	u := testUser(t)
+	u.Password = ""
+	u.Timezone = "Europe/Moscow"
+	assert.NoError(t, u.ValidateUpdate())
+
+	u.Timezone = "Mars/Olympus"
+	assert.Error(t, u.ValidateUpdate())
+
+	u.Timezone = ""
+	u.Interests = strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")
+	assert.Error(t, u.ValidateUpdate())
+}
+
 func TestUser_EncryptPassword(t *testing.T) {
 	u := testUser(t)
 	assert.NoError(t, u.EncryptPassword())
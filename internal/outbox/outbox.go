@@ -0,0 +1,224 @@
+// Package outbox delivers the domain events written to the outbox to the
+// in-process subscribers.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+var (
+	errHandlerPanic  = errors.New("handler panicked")
+	errNoSubscribers = errors.New("no subscribers")
+)
+
+// Handler handles a domain event. A returned error makes the dispatcher try
+// the message again later. Delivery is at least once, so handlers must be
+// idempotent.
+type Handler func(ctx context.Context, m *model.OutboxMessage) error
+
+// Record writes a domain event of eventType with payload to the outbox of
+// tx, to be delivered once tx is committed.
+func Record(ctx context.Context, tx store.Store, eventType string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Outbox().Create(ctx, &model.OutboxMessage{Type: eventType, Payload: b})
+}
+
+type Config struct {
+	// Interval is how long the dispatcher sleeps once the outbox is drained.
+	Interval  time.Duration
+	BatchSize int
+	// MaxAttempts is how many times a message is tried before it is dead.
+	MaxAttempts int
+	// The n-th retry waits MinBackoff * 2^(n-1), but no longer than MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed message is hidden from other dispatchers.
+	// A message whose dispatcher died is tried again once it ends.
+	Lease time.Duration
+	// Timeout bounds the handling of one message by all of its subscribers.
+	Timeout time.Duration
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Interval:    time.Second,
+		BatchSize:   20,
+		MaxAttempts: 10,
+		MinBackoff:  time.Second,
+		MaxBackoff:  10 * time.Minute,
+		Lease:       5 * time.Minute,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Dispatcher polls the outbox and hands every message to the subscribers of
+// its type. Delivered messages are removed; the ones that kept failing are
+// left dead for an operator to look into. A message nobody subscribes to
+// fails like any other, so it is never dropped unhandled.
+type Dispatcher struct {
+	store    store.Store
+	config   *Config
+	logger   *log.Logger
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewDispatcher returns a Dispatcher of the outbox of st. Errors are logged
+// to logger.
+func NewDispatcher(st store.Store, c *Config, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:    st,
+		config:   c,
+		logger:   logger,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe makes h handle the messages of eventType.
+func (d *Dispatcher) Subscribe(eventType string, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handlers[eventType] = append(d.handlers[eventType], h)
+}
+
+// Start dispatches the outbox in the background until Close.
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go d.run(ctx)
+}
+
+// Close stops the dispatcher started with Start and waits for it. The
+// messages it has claimed but not handled are tried again after their lease.
+func (d *Dispatcher) Close() {
+	if d.cancel == nil {
+		return
+	}
+
+	d.cancel()
+	<-d.done
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	defer close(d.done)
+
+	for {
+		n, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Println("Cannot dispatch outbox:", err)
+		}
+
+		// A full batch suggests more messages are due.
+		if err == nil && n == d.config.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.config.Interval):
+		}
+	}
+}
+
+// Dispatch handles one batch of the due messages and returns its size.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	messages, err := d.store.Outbox().Claim(ctx, time.Now(), d.config.Lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range messages {
+		if err := d.deliver(ctx, m); err != nil {
+			// The message was not handled because of the shutdown, so it
+			// does not count as an attempt.
+			if ctx.Err() != nil {
+				return len(messages), ctx.Err()
+			}
+
+			if err := d.fail(ctx, m, err); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+
+		if err := d.store.Outbox().DeleteByID(ctx, m.ID); err != nil {
+			return len(messages), err
+		}
+	}
+
+	return len(messages), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, m *model.OutboxMessage) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%w: %v", errHandlerPanic, v)
+		}
+	}()
+
+	d.mu.RLock()
+	handlers := d.handlers[m.Type]
+	d.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		return errNoSubscribers
+	}
+
+	for _, h := range handlers {
+		if err := h(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fail schedules the next attempt of m or, after the last one, marks it dead.
+func (d *Dispatcher) fail(ctx context.Context, m *model.OutboxMessage, err error) error {
+	m.Attempts++
+	m.LastError = err.Error()
+
+	if m.Attempts >= d.config.MaxAttempts {
+		m.Status = model.OutboxDead
+		d.logger.Printf("Outbox message %d (%s) is dead after %d attempts: %v", m.ID, m.Type, m.Attempts, err)
+	} else {
+		m.NextAttemptAt = time.Now().Add(d.backoff(m.Attempts))
+	}
+
+	return d.store.Outbox().Update(ctx, m)
+}
+
+// backoff returns the delay before the retry that follows attempts failures.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.MinBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+	return delay
+}
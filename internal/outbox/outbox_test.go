@@ -0,0 +1,169 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/outbox"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDispatcher(st store.Store) *outbox.Dispatcher {
+	c := outbox.NewConfig()
+	c.MaxAttempts = 3
+	c.MinBackoff = 0
+	c.Interval = 10 * time.Millisecond
+
+	return outbox.NewDispatcher(st, c, log.New(io.Discard, "", 0))
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	st := teststore.New()
+	d := testDispatcher(st)
+	ctx := context.Background()
+
+	got := make([][]string, 2)
+	for i := range got {
+		i := i
+		d.Subscribe(model.DomainUserDeleted, func(ctx context.Context, m *model.OutboxMessage) error {
+			got[i] = append(got[i], string(m.Payload))
+			return nil
+		})
+	}
+
+	err := st.WithTx(ctx, func(tx store.Store) error {
+		if err := outbox.Record(ctx, tx, model.DomainUserDeleted, &model.DeletedUser{UserID: 1}); err != nil {
+			return err
+		}
+		return outbox.Record(ctx, tx, model.DomainUserRegistered, &model.User{ID: 2})
+	})
+	assert.NoError(t, err)
+
+	assert.Error(t, st.WithTx(ctx, func(tx store.Store) error {
+		if err := outbox.Record(ctx, tx, model.DomainUserDeleted, &model.DeletedUser{UserID: 3}); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	}))
+
+	n, err := d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	for _, g := range got {
+		assert.Equal(t, []string{`{"user_id":1}`}, g)
+	}
+
+	// The message nobody subscribes to is kept and goes dead in the end.
+	for i := 0; i < 2; i++ {
+		n, err = d.Dispatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	n, err = d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	dead, err := st.Outbox().FindByStatus(ctx, model.OutboxDead, 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, model.DomainUserRegistered, dead[0].Type)
+		assert.Equal(t, "no subscribers", dead[0].LastError)
+	}
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	st := teststore.New()
+	d := testDispatcher(st)
+	ctx := context.Background()
+
+	calls := 0
+	d.Subscribe(model.DomainLikeCreated, func(ctx context.Context, m *model.OutboxMessage) error {
+		calls++
+		if calls == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	d.Subscribe(model.DomainMatchCreated, func(ctx context.Context, m *model.OutboxMessage) error {
+		panic("broken")
+	})
+
+	assert.NoError(t, outbox.Record(ctx, st, model.DomainLikeCreated, &model.Like{ID: 1}))
+	assert.NoError(t, outbox.Record(ctx, st, model.DomainMatchCreated, &model.Match{ID: 1}))
+
+	for i := 0; i < 3; i++ {
+		_, err := d.Dispatch(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+
+	pending, err := st.Outbox().FindByStatus(ctx, model.OutboxPending, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	dead, err := st.Outbox().FindByStatus(ctx, model.OutboxDead, 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, model.DomainMatchCreated, dead[0].Type)
+		assert.Equal(t, 3, dead[0].Attempts)
+		assert.Contains(t, dead[0].LastError, "broken")
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	st := teststore.New()
+	c := outbox.NewConfig()
+	d := outbox.NewDispatcher(st, c, log.New(io.Discard, "", 0))
+	ctx := context.Background()
+
+	d.Subscribe(model.DomainLikeCreated, func(ctx context.Context, m *model.OutboxMessage) error {
+		return errors.New("unavailable")
+	})
+	assert.NoError(t, outbox.Record(ctx, st, model.DomainLikeCreated, &model.Like{ID: 1}))
+
+	n, err := d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	claimed, err := st.Outbox().Claim(ctx, time.Now().Add(c.MinBackoff), time.Minute, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, 1, claimed[0].Attempts)
+		assert.Equal(t, model.OutboxPending, claimed[0].Status)
+	}
+}
+
+func TestDispatcher_Start(t *testing.T) {
+	st := teststore.New()
+	d := testDispatcher(st)
+
+	got := make(chan *model.OutboxMessage, 1)
+	d.Subscribe(model.DomainUserDeleted, func(ctx context.Context, m *model.OutboxMessage) error {
+		got <- m
+		return nil
+	})
+
+	d.Start()
+	defer d.Close()
+
+	assert.NoError(t, outbox.Record(context.Background(), st, model.DomainUserDeleted, &model.DeletedUser{UserID: 1}))
+
+	select {
+	case m := <-got:
+		assert.JSONEq(t, `{"user_id":1}`, string(m.Payload))
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
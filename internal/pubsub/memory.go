@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Memory delivers payloads within the process. It suits a single instance.
+type Memory struct {
+	subscribers
+	closed atomic.Bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Publish(ctx context.Context, payload []byte) error {
+	if m.closed.Load() {
+		return ErrClosed
+	}
+
+	m.deliver(payload)
+
+	return nil
+}
+
+func (m *Memory) Subscribe(fn func(payload []byte)) {
+	m.add(fn)
+}
+
+func (m *Memory) Close() error {
+	m.closed.Store(true)
+	return nil
+}
@@ -0,0 +1,30 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_Publish(t *testing.T) {
+	ps := pubsub.NewMemory()
+
+	got := make([][]string, 2)
+	for i := range got {
+		i := i
+		ps.Subscribe(func(payload []byte) {
+			got[i] = append(got[i], string(payload))
+		})
+	}
+
+	assert.NoError(t, ps.Publish(context.Background(), []byte("first")))
+	assert.NoError(t, ps.Publish(context.Background(), []byte("second")))
+	for _, g := range got {
+		assert.Equal(t, []string{"first", "second"}, g)
+	}
+
+	assert.NoError(t, ps.Close())
+	assert.ErrorIs(t, ps.Publish(context.Background(), []byte("third")), pubsub.ErrClosed)
+}
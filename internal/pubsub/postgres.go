@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// maxNotifyPayload is the longest payload sent within a notification;
+	// Postgres refuses 8000 bytes and more.
+	maxNotifyPayload = 7900
+	// payloadTTL is how long the payloads too long for a notification are
+	// kept for the listeners to read.
+	payloadTTL = time.Minute
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Prefixes of notification payloads.
+const (
+	inlinePrefix = "="
+	refPrefix    = "@"
+)
+
+// Postgres delivers payloads to every instance listening on the same
+// channel of the database with LISTEN/NOTIFY. Payloads too long for a
+// notification go through the pubsub_payloads table.
+type Postgres struct {
+	subscribers
+	pool    *pgxpool.Pool
+	channel string
+	logger  *log.Logger
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewPostgres starts listening on channel with a connection of its own,
+// reconnecting until closed. Errors are logged to logger.
+func NewPostgres(pool *pgxpool.Pool, channel string, logger *log.Logger) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		pool:    pool,
+		channel: channel,
+		logger:  logger,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go p.run(ctx)
+
+	return p
+}
+
+func (p *Postgres) Publish(ctx context.Context, payload []byte) error {
+	select {
+	case <-p.done:
+		return ErrClosed
+	default:
+	}
+
+	if len(payload) <= maxNotifyPayload {
+		_, err := p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", p.channel, inlinePrefix+string(payload))
+		return err
+	}
+
+	_, err := p.pool.Exec(
+		ctx,
+		`WITH expired AS (
+			DELETE FROM pubsub_payloads WHERE created_at < now() - $3::interval
+		), stored AS (
+			INSERT INTO pubsub_payloads(payload) VALUES ($2) RETURNING payload_id
+		)
+		SELECT pg_notify($1, $4::text || payload_id) FROM stored`,
+		p.channel, payload, payloadTTL, refPrefix,
+	)
+
+	return err
+}
+
+func (p *Postgres) Subscribe(fn func(payload []byte)) {
+	p.add(fn)
+}
+
+// Close stops listening and waits for the listener to quit.
+func (p *Postgres) Close() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// run listens until ctx is done, backing off between reconnects.
+func (p *Postgres) run(ctx context.Context) {
+	defer close(p.done)
+
+	delay := minReconnectDelay
+	for {
+		err := p.listen(ctx, func() { delay = minReconnectDelay })
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.logger.Printf("Cannot listen on %s, reconnecting in %v: %v\n", p.channel, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// listen delivers the notifications of a single connection. The connection
+// is taken out of the pool, as it stays subscribed to the channel.
+func (p *Postgres) listen(ctx context.Context, listening func()) error {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return err
+	}
+	listening()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		payload, err := p.resolve(ctx, conn, n.Payload)
+		if err != nil {
+			p.logger.Printf("Cannot read notification on %s: %v\n", p.channel, err)
+			continue
+		}
+
+		p.deliver(payload)
+	}
+}
+
+// resolve returns the payload a notification carries or refers to.
+func (p *Postgres) resolve(ctx context.Context, conn *pgx.Conn, s string) ([]byte, error) {
+	if len(s) > 0 && s[:1] == inlinePrefix {
+		return []byte(s[1:]), nil
+	}
+
+	if len(s) == 0 || s[:1] != refPrefix {
+		return nil, errUnknownNotification
+	}
+
+	id, err := strconv.ParseInt(s[1:], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	err = conn.QueryRow(ctx, "SELECT payload FROM pubsub_payloads WHERE payload_id = $1", id).Scan(&payload)
+
+	return payload, err
+}
@@ -0,0 +1,46 @@
+// Package pubsub fans payloads out to the subscribers of every API instance.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrClosed              = errors.New("pubsub is closed")
+	errUnknownNotification = errors.New("unknown notification format")
+)
+
+// PubSub delivers every published payload to every subscriber. Delivery is
+// best effort: payloads published while a subscriber cannot be reached are
+// lost.
+type PubSub interface {
+	// Publish sends payload, which must be UTF-8 text, to the subscribers.
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe makes fn receive the published payloads. fn must not block.
+	Subscribe(fn func(payload []byte))
+	Close() error
+}
+
+// subscribers is the subscriber list of an implementation.
+type subscribers struct {
+	mu  sync.RWMutex
+	fns []func(payload []byte)
+}
+
+func (s *subscribers) add(fn func(payload []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fns = append(s.fns, fn)
+}
+
+func (s *subscribers) deliver(payload []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, fn := range s.fns {
+		fn(payload)
+	}
+}
@@ -0,0 +1,159 @@
+// Package ranking orders discovery candidates by a weighted sum of scores.
+package ranking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+var ErrUnknownScorer = errors.New("unknown scorer")
+
+// Context is what scorers know besides the candidate itself.
+type Context struct {
+	Viewer *model.User
+	// LikedBy holds the ids of the candidates that already liked Viewer.
+	LikedBy map[int]bool
+	Now     time.Time
+}
+
+// Scorer rates how good a candidate is for the viewer, from 0 to 1.
+type Scorer interface {
+	Name() string
+	Score(c *Context, u *model.User) float64
+}
+
+// Score is the ranking of one candidate with the weighted contribution of
+// every scorer.
+type Score struct {
+	UserID    int                `json:"user_id"`
+	Total     float64            `json:"total"`
+	Breakdown map[string]float64 `json:"breakdown"`
+}
+
+type weighted struct {
+	scorer Scorer
+	weight float64
+}
+
+// Ranker sums the scores of its scorers multiplied by their weights.
+type Ranker struct {
+	scorers []weighted
+}
+
+// New returns a Ranker of the built-in scorers with the given weights.
+// Scorers with a zero weight are left out.
+func New(weights map[string]float64) (*Ranker, error) {
+	r := &Ranker{}
+	for name, w := range weights {
+		s, ok := builtin[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScorer, name)
+		}
+		r.Add(s, w)
+	}
+
+	sort.Slice(r.scorers, func(i, j int) bool {
+		return r.scorers[i].scorer.Name() < r.scorers[j].scorer.Name()
+	})
+
+	return r, nil
+}
+
+// Default returns a Ranker with DefaultWeights.
+func Default() *Ranker {
+	r, err := New(DefaultWeights)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Add makes r use s with weight w.
+func (r *Ranker) Add(s Scorer, w float64) {
+	if w != 0 {
+		r.scorers = append(r.scorers, weighted{s, w})
+	}
+}
+
+// Rank orders users by total score, best first, keeping the original order
+// of equal ones, and returns their scores in the same order.
+func (r *Ranker) Rank(c *Context, users []*model.User) []*Score {
+	scores := make(map[int]*Score, len(users))
+	for _, u := range users {
+		s := &Score{
+			UserID:    u.ID,
+			Breakdown: make(map[string]float64, len(r.scorers)),
+		}
+
+		for _, ws := range r.scorers {
+			v := ws.weight * ws.scorer.Score(c, u)
+			s.Breakdown[ws.scorer.Name()] = v
+			s.Total += v
+		}
+
+		scores[u.ID] = s
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		return scores[users[i].ID].Total > scores[users[j].ID].Total
+	})
+
+	ranked := make([]*Score, 0, len(users))
+	for _, u := range users {
+		ranked = append(ranked, scores[u.ID])
+	}
+
+	return ranked
+}
+
+// DefaultWeights favor people who already liked the viewer, then closeness
+// in interests, distance and activity.
+var DefaultWeights = map[string]float64{
+	"liked_you":    2,
+	"interests":    1,
+	"distance":     1,
+	"recency":      1,
+	"completeness": 0.5,
+}
+
+// ParseWeights reads "name=weight" pairs separated by commas on top of
+// DefaultWeights. An empty string gives the defaults.
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(DefaultWeights))
+	for name, w := range DefaultWeights {
+		weights[name] = w
+	}
+
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q: expected name=weight", pair)
+		}
+
+		if _, ok := builtin[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScorer, name)
+		}
+
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of %s: %w", name, err)
+		}
+		if w < 0 {
+			return nil, fmt.Errorf("invalid weight of %s: must not be negative", name)
+		}
+
+		weights[name] = w
+	}
+
+	return weights, nil
+}
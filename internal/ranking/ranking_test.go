@@ -0,0 +1,118 @@
+package ranking_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/ranking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRanker_Rank(t *testing.T) {
+	now := time.Now()
+	viewer := &model.User{
+		ID:        1,
+		Interests: []string{"music", "hiking"},
+		Location:  &model.Location{Latitude: 55.75, Longitude: 37.62},
+	}
+
+	users := []*model.User{
+		{ID: 2},
+		{ID: 3, Interests: []string{"music", "hiking"}, LastSeenAt: now},
+		{ID: 4, Location: &model.Location{Latitude: 55.75, Longitude: 37.62}},
+		{ID: 5},
+	}
+
+	r, err := ranking.New(map[string]float64{
+		"interests": 1,
+		"distance":  0.5,
+		"liked_you": 2,
+		"recency":   0,
+	})
+	assert.NoError(t, err)
+
+	scores := r.Rank(&ranking.Context{
+		Viewer:  viewer,
+		LikedBy: map[int]bool{5: true},
+		Now:     now,
+	}, users)
+
+	ids := make([]int, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	assert.Equal(t, []int{5, 3, 4, 2}, ids)
+
+	assert.Equal(t, 5, scores[0].UserID)
+	assert.Equal(t, 2.0, scores[0].Total)
+	assert.Equal(t, map[string]float64{"distance": 0, "interests": 0, "liked_you": 2}, scores[0].Breakdown)
+	// The distance scored is the one shown, at least a kilometer.
+	assert.InDelta(t, 0.5/1.1, scores[2].Breakdown["distance"], 1e-9)
+}
+
+func TestNew_UnknownScorer(t *testing.T) {
+	_, err := ranking.New(map[string]float64{"popularity": 1})
+	assert.ErrorIs(t, err, ranking.ErrUnknownScorer)
+}
+
+func TestParseWeights(t *testing.T) {
+	testCases := []struct {
+		name    string
+		s       string
+		isValid bool
+	}{
+		{
+			name:    "empty",
+			s:       "",
+			isValid: true,
+		},
+		{
+			name:    "overrides",
+			s:       "recency=2, distance=0",
+			isValid: true,
+		},
+		{
+			name:    "unknown scorer",
+			s:       "popularity=1",
+			isValid: false,
+		},
+		{
+			name:    "missing weight",
+			s:       "recency",
+			isValid: false,
+		},
+		{
+			name:    "negative weight",
+			s:       "recency=-1",
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ranking.ParseWeights(tc.s)
+			if tc.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	weights, err := ranking.ParseWeights("recency=2,distance=0")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, weights["recency"])
+	assert.Equal(t, 0.0, weights["distance"])
+	assert.Equal(t, ranking.DefaultWeights["interests"], weights["interests"])
+}
+
+func TestRecency_Score(t *testing.T) {
+	now := time.Now()
+	s := ranking.Recency{HalfLife: time.Hour}
+	c := &ranking.Context{Now: now}
+
+	assert.Equal(t, 0.0, s.Score(c, &model.User{}))
+	assert.Equal(t, 1.0, s.Score(c, &model.User{LastSeenAt: now}))
+	assert.InDelta(t, 0.25, s.Score(c, &model.User{LastSeenAt: now.Add(-2 * time.Hour)}), 1e-9)
+}
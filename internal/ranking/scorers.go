@@ -0,0 +1,132 @@
+package ranking
+
+import (
+	"math"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+var builtin = map[string]Scorer{
+	"recency":      Recency{HalfLife: 72 * time.Hour},
+	"completeness": Completeness{},
+	"interests":    SharedInterests{},
+	"distance":     Distance{Scale: 10},
+	"liked_you":    LikedYou{},
+}
+
+// Recency halves the score of a candidate for every HalfLife since they were
+// last seen. Candidates never seen score 0.
+type Recency struct {
+	HalfLife time.Duration
+}
+
+func (Recency) Name() string {
+	return "recency"
+}
+
+func (s Recency) Score(c *Context, u *model.User) float64 {
+	if u.LastSeenAt.IsZero() {
+		return 0
+	}
+
+	idle := c.Now.Sub(u.LastSeenAt)
+	if idle <= 0 {
+		return 1
+	}
+
+	return math.Pow(0.5, float64(idle)/float64(s.HalfLife))
+}
+
+// Completeness is the share of the optional profile fields that are filled.
+type Completeness struct{}
+
+func (Completeness) Name() string {
+	return "completeness"
+}
+
+func (Completeness) Score(c *Context, u *model.User) float64 {
+	filled := 0
+	if u.About != "" {
+		filled++
+	}
+	if u.Location != nil {
+		filled++
+	}
+	if len(u.Interests) != 0 {
+		filled++
+	}
+
+	return float64(filled) / 3
+}
+
+// SharedInterests is the Jaccard index of the interests of the viewer and
+// the candidate.
+type SharedInterests struct{}
+
+func (SharedInterests) Name() string {
+	return "interests"
+}
+
+func (SharedInterests) Score(c *Context, u *model.User) float64 {
+	if len(c.Viewer.Interests) == 0 || len(u.Interests) == 0 {
+		return 0
+	}
+
+	own := make(map[string]bool, len(c.Viewer.Interests))
+	for _, i := range c.Viewer.Interests {
+		own[i] = true
+	}
+
+	union := len(own)
+	shared := 0
+	seen := make(map[string]bool, len(u.Interests))
+	for _, i := range u.Interests {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+
+		if own[i] {
+			shared++
+		} else {
+			union++
+		}
+	}
+
+	return float64(shared) / float64(union)
+}
+
+// Distance scores 1/(1 + d/Scale), 1/2 at Scale kilometers and less further
+// away. d is the rounded distance users are shown, since the score is shown
+// in debug mode. Candidates without a location score 0.
+type Distance struct {
+	Scale float64
+}
+
+func (Distance) Name() string {
+	return "distance"
+}
+
+func (s Distance) Score(c *Context, u *model.User) float64 {
+	if c.Viewer.Location == nil || u.Location == nil {
+		return 0
+	}
+
+	return 1 / (1 + float64(c.Viewer.Location.ApproxDistanceTo(u.Location))/s.Scale)
+}
+
+// LikedYou scores the candidates who already liked the viewer: a like from
+// the viewer is certain to become a match.
+type LikedYou struct{}
+
+func (LikedYou) Name() string {
+	return "liked_you"
+}
+
+func (LikedYou) Score(c *Context, u *model.User) float64 {
+	if c.LikedBy[u.ID] {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package store
+
+import "errors"
+
+// Repositories return these instead of driver errors, so callers can react to
+// a failure without knowing the database behind the store.
+var (
+	ErrNotFound        = errors.New("record not found")
+	ErrDuplicateLogin  = errors.New("login is already taken")
+	ErrDuplicatePhone  = errors.New("phone number is already taken")
+	ErrSelfLike        = errors.New("user cannot like themselves")
+	ErrAlreadyLiked    = errors.New("user is already liked")
+	ErrSelfMatch       = errors.New("user cannot match themselves")
+	ErrAlreadyMatched  = errors.New("users are already matched")
+	ErrSelfPass        = errors.New("user cannot pass themselves")
+	ErrAlreadyPassed   = errors.New("user is already passed")
+	ErrSelfBlock       = errors.New("user cannot block themselves")
+	ErrAlreadyBlocked  = errors.New("user is already blocked")
+	ErrSelfReport      = errors.New("user cannot report themselves")
+	ErrInvalidData     = errors.New("data violates a constraint")
+	ErrStillReferenced = errors.New("record is still referenced")
+	ErrInvalidPhoto    = errors.New("photo must be a jpeg image")
+	ErrNoLocation      = errors.New("location is not set")
+	ErrSuperLikeQuota  = errors.New("daily super like quota is used up")
+	ErrLimitExceeded   = errors.New("limit is exceeded")
+	ErrThreadLocked    = errors.New("thread is locked")
+)
@@ -1,4 +1,4 @@
-package store
+package filestore
 
 import (
 	"bufio"
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"os"
+
+	"github.com/kek-flip/scotch-api/internal/store"
 )
 
 type PhotoStore struct {
@@ -16,7 +18,7 @@ type PhotoStore struct {
 
 var errDirNotExist = errors.New("directory does not exist")
 
-func NewPhotoStore(path string) (*PhotoStore, error) {
+func New(path string) (*PhotoStore, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, errDirNotExist
 	}
@@ -25,6 +27,10 @@ func NewPhotoStore(path string) (*PhotoStore, error) {
 }
 
 func (ps *PhotoStore) Create(photo []byte, id int) error {
+	if http.DetectContentType(photo) != "image/jpeg" {
+		return store.ErrInvalidPhoto
+	}
+
 	fullFileName := fmt.Sprintf("%s/%d.jpeg", ps.path, id)
 	pf, err := os.Create(fullFileName)
 	if err != nil {
@@ -32,10 +38,6 @@ func (ps *PhotoStore) Create(photo []byte, id int) error {
 	}
 	defer pf.Close()
 
-	if http.DetectContentType(photo) != "image/jpeg" {
-		return errors.New("not jpeg image")
-	}
-
 	n, err := pf.Write(photo)
 	if len(photo) != n {
 		os.Remove(fullFileName)
@@ -48,6 +50,9 @@ func (ps *PhotoStore) Create(photo []byte, id int) error {
 func (ps *PhotoStore) FindById(id int) ([]byte, error) {
 	fullFileName := fmt.Sprintf("%s/%d.jpeg", ps.path, id)
 	pf, err := os.OpenFile(fullFileName, os.O_RDONLY, 0666)
+	if os.IsNotExist(err) {
+		return nil, store.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -65,5 +70,10 @@ func (ps *PhotoStore) FindById(id int) ([]byte, error) {
 
 func (ps *PhotoStore) DeleteByName(fileName string) error {
 	fullFileName := fmt.Sprintf("%s/%s.jpeg", ps.path, fileName)
-	return os.Remove(fullFileName)
+	err := os.Remove(fullFileName)
+	if os.IsNotExist(err) {
+		return store.ErrNotFound
+	}
+
+	return err
 }
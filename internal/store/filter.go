@@ -0,0 +1,80 @@
+package store
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+const (
+	SortByID      = "id"
+	SortByAgeAsc  = "age"
+	SortByAgeDesc = "-age"
+	// SortByLastSeen puts the users seen most recently first and the ones
+	// never seen last.
+	SortByLastSeen = "-last_seen"
+)
+
+// UserFilter describes a discovery query. Zero values mean "any".
+// Mutual additionally leaves out the users whose stored preferences the
+// current user does not fit. MaxDistance is in kilometers from Origin, the
+// location of the current user, and requires it to be set.
+type UserFilter struct {
+	MinAge      int             `json:"min_age"`
+	MaxAge      int             `json:"max_age"`
+	Gender      string          `json:"gender"`
+	Genders     []string        `json:"genders"`
+	Cities      []string        `json:"cities"`
+	HasAbout    bool            `json:"has_about"`
+	Mutual      bool            `json:"mutual"`
+	MaxDistance int             `json:"max_distance"`
+	Origin      *model.Location `json:"-"`
+	Sort        string          `json:"sort"`
+	Pagination
+}
+
+// NewUserFilter returns the filter that finds the users fitting p.
+func NewUserFilter(p *model.Preferences) *UserFilter {
+	return &UserFilter{
+		MinAge:      p.MinAge,
+		MaxAge:      p.MaxAge,
+		Genders:     p.Genders,
+		Cities:      p.Cities,
+		Mutual:      p.Mutual,
+		MaxDistance: p.MaxDistance,
+	}
+}
+
+func (f *UserFilter) Validate() error {
+	return validation.ValidateStruct(
+		f,
+		validation.Field(&f.MinAge, validation.Min(0), validation.Max(99)),
+		validation.Field(&f.MaxAge, validation.Min(0), validation.Max(99)),
+		validation.Field(&f.Gender, validation.In("male", "female")),
+		validation.Field(&f.Genders, validation.Each(validation.In("male", "female"))),
+		validation.Field(&f.MaxDistance, validation.Min(0), validation.Max(20000)),
+		validation.Field(&f.Sort, validation.In(SortByID, SortByAgeAsc, SortByAgeDesc, SortByLastSeen)),
+		validation.Field(&f.Pagination),
+	)
+}
+
+// UserCursor is the keyset position of the last user on a page.
+type UserCursor struct {
+	Age      int        `json:"a,omitempty"`
+	LastSeen *time.Time `json:"s,omitempty"`
+	ID       int        `json:"id"`
+}
+
+// NextUserCursor returns the cursor pointing past u for the given sort order.
+func NextUserCursor(sort string, u *model.User) string {
+	c := UserCursor{ID: u.ID}
+	if sort == SortByAgeAsc || sort == SortByAgeDesc {
+		c.Age = u.Age
+	}
+	if sort == SortByLastSeen {
+		c.LastSeen = &u.LastSeenAt
+	}
+
+	return EncodeCursor(c)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Pagination selects one page of a keyset-paginated list. An empty Cursor
+// requests the first page.
+type Pagination struct {
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+func (p *Pagination) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.Limit, validation.Min(0), validation.Max(MaxLimit)),
+	)
+}
+
+// PageLimit returns the requested page size or the default one.
+func (p *Pagination) PageLimit() int {
+	if p.Limit <= 0 {
+		return DefaultLimit
+	}
+	return p.Limit
+}
+
+// IDCursor is the keyset position of lists ordered by their primary key.
+type IDCursor struct {
+	ID int `json:"id"`
+}
+
+// AfterID decodes the cursor of p and returns the id the page starts after.
+func (p *Pagination) AfterID() (int, error) {
+	if p.Cursor == "" {
+		return 0, nil
+	}
+
+	c := &IDCursor{}
+	if err := DecodeCursor(p.Cursor, c); err != nil {
+		return 0, err
+	}
+
+	return c.ID, nil
+}
+
+// BeforeID decodes the cursor of a list ordered from the newest and returns
+// the id the page starts before, or 0 for the first page.
+func (p *Pagination) BeforeID() (int, error) {
+	return p.AfterID()
+}
+
+type UserPage struct {
+	Users      []*model.User `json:"users"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+type LikePage struct {
+	Likes      []*model.Like `json:"likes"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+type MatchPage struct {
+	Matches    []*model.Match `json:"matches"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+type OutboxPage struct {
+	Messages   []*model.OutboxMessage `json:"messages"`
+	NextCursor string                 `json:"next_cursor"`
+}
+
+type MessagePage struct {
+	Messages   []*model.Message `json:"messages"`
+	NextCursor string           `json:"next_cursor"`
+}
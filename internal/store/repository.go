@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+// Lists of users, likes and matches leave out the pairs in which either user
+// has blocked the other.
+type UserRepository interface {
+	Create(ctx context.Context, u *model.User) error
+	All(ctx context.Context, currentUserID int) ([]*model.User, error)
+	AllPage(ctx context.Context, currentUserID int, p *Pagination) (*UserPage, error)
+	FindByFilters(ctx context.Context, currentUserID int, f *UserFilter) (*UserPage, error)
+	// Feed is FindByFilters without the users currentUserID has already
+	// liked, passed or matched with.
+	Feed(ctx context.Context, currentUserID int, f *UserFilter) (*UserPage, error)
+	Count(ctx context.Context) (int, error)
+	FindById(ctx context.Context, id int) (*model.User, error)
+	FindByIDs(ctx context.Context, ids []int) ([]*model.User, error)
+	FindByLogin(ctx context.Context, login string) (*model.User, error)
+	Update(ctx context.Context, u *model.User) error
+	UpdateLocation(ctx context.Context, id int, l *model.Location) error
+	UpdateLastSeen(ctx context.Context, id int, t time.Time) error
+	DeleteById(ctx context.Context, id int) error
+}
+
+// LikeRepository refuses super likes beyond the daily quota of the user,
+// counted from midnight in the user's timezone, with ErrSuperLikeQuota.
+type LikeRepository interface {
+	Create(ctx context.Context, l *model.Like) error
+	// Swipe creates l and, if it is reciprocal, the match of the pair as
+	// one atomic operation. The match is nil when the like did not produce one.
+	Swipe(ctx context.Context, l *model.Like) (*model.Match, error)
+	SuperLikeQuota(ctx context.Context, userID int) (*model.Quota, error)
+	FindMatchLike(ctx context.Context, l *model.Like) (*model.Like, error)
+	// FindLastByUser returns the most recent like userID has given.
+	FindLastByUser(ctx context.Context, userID int) (*model.Like, error)
+	FindByUserID(ctx context.Context, userID int) ([]*model.Like, error)
+	FindByLikedUser(ctx context.Context, likedUser int) ([]*model.Like, error)
+	// FindLikers returns the ones of userIDs who liked likedUser.
+	FindLikers(ctx context.Context, likedUser int, userIDs []int) ([]int, error)
+	FindPageByUserID(ctx context.Context, userID int, p *Pagination) (*LikePage, error)
+	FindPageByLikedUser(ctx context.Context, likedUser int, p *Pagination) (*LikePage, error)
+	DeleteByUsers(ctx context.Context, userId, likedUser int) error
+	DeleteByUser(ctx context.Context, userID int) error
+	DeleteByLikedUser(ctx context.Context, likedUser int) error
+}
+
+type MatchRepository interface {
+	Create(ctx context.Context, m *model.Match) error
+	FindByUser(ctx context.Context, userID int) ([]*model.Match, error)
+	FindByUsers(ctx context.Context, user1, user2 int) (*model.Match, error)
+	FindPageByUser(ctx context.Context, userID int, p *Pagination) (*MatchPage, error)
+	DeleteByUsers(ctx context.Context, user1, user2 int) error
+	DeleteByUser(ctx context.Context, id int) error
+}
+
+type PassRepository interface {
+	Create(ctx context.Context, p *model.Pass) error
+	// FindLastByUser returns the most recent pass userID has given.
+	FindLastByUser(ctx context.Context, userID int) (*model.Pass, error)
+	DeleteByID(ctx context.Context, id int) error
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type PreferencesRepository interface {
+	FindByUser(ctx context.Context, userID int) (*model.Preferences, error)
+	Save(ctx context.Context, p *model.Preferences) error
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type UnmatchRepository interface {
+	Create(ctx context.Context, u *model.Unmatch) error
+	FindByUser(ctx context.Context, userID int) ([]*model.Unmatch, error)
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type BlockRepository interface {
+	Create(ctx context.Context, b *model.Block) error
+	// Exists reports whether either of the users has blocked the other.
+	Exists(ctx context.Context, user1, user2 int) (bool, error)
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+// ReportRepository is the moderation queue. Reports outlive the account of
+// the reported user, so that deleting it does not clear the queue.
+type ReportRepository interface {
+	Create(ctx context.Context, r *model.Report) error
+	FindByReportedUser(ctx context.Context, reportedUser int) ([]*model.Report, error)
+	// DeleteByUser deletes the reports userID has filed.
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+// MessageRepository keeps the conversations of matches. Messages are not
+// removed with their match, so a thread stays readable after an unmatch.
+type MessageRepository interface {
+	// Create sends m from m.Sender to the other user of the match and sets
+	// m.Recipient. It fails with ErrThreadLocked if the match no longer
+	// exists or m.Sender is not in it.
+	Create(ctx context.Context, m *model.Message) error
+	// FindThread returns the thread of the match, locked if the match was
+	// dissolved after its users had talked.
+	FindThread(ctx context.Context, matchID int) (*model.Thread, error)
+	// FindPageByMatch lists the messages of the match from the newest.
+	FindPageByMatch(ctx context.Context, matchID int, p *Pagination) (*MessagePage, error)
+	// Update replaces the body of the message with m.ID, m.MatchID and
+	// m.Sender, marks it edited and fills in m. Deleted messages are not
+	// found. It fails with ErrThreadLocked like Create.
+	Update(ctx context.Context, m *model.Message) error
+	// Delete clears the body of the message with m.ID, m.MatchID and
+	// m.Sender, marks it deleted and fills in m. Deleted messages are not
+	// found. It fails with ErrThreadLocked like Create.
+	Delete(ctx context.Context, m *model.Message) error
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+// EventRepository is the event log of every user.
+type EventRepository interface {
+	// Create logs e. The events of one user become visible in the order of
+	// their IDs, so FindAfter never skips an event that commits late.
+	Create(ctx context.Context, e *model.Event) error
+	// FindAfter returns up to limit events of userID that follow afterID,
+	// oldest first.
+	FindAfter(ctx context.Context, userID, afterID, limit int) ([]*model.Event, error)
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+// OutboxRepository keeps the domain events until the dispatcher has
+// delivered them. Messages are created in the transaction of the change they
+// describe.
+type OutboxRepository interface {
+	Create(ctx context.Context, m *model.OutboxMessage) error
+	// Claim returns up to limit pending messages whose next attempt is due
+	// at now, oldest first, and postpones that attempt by lease, so that no
+	// other dispatcher claims them in the meantime.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error)
+	// FindByStatus returns up to limit messages in status that follow
+	// afterID, oldest first.
+	FindByStatus(ctx context.Context, status string, afterID, limit int) ([]*model.OutboxMessage, error)
+	// Update saves the status, attempts, next attempt and last error of m.
+	Update(ctx context.Context, m *model.OutboxMessage) error
+	// Replay makes the dead message with id pending again with no attempts,
+	// due at now, and returns it. It fails with ErrNotFound if there is no
+	// dead message with id.
+	Replay(ctx context.Context, id int, now time.Time) (*model.OutboxMessage, error)
+	DeleteByID(ctx context.Context, id int) error
+}
+
+type CounterRepository interface {
+	// Increment adds one to c, creating it if needed, and sets c.Count.
+	// A counter that already reached max is left as is and ErrLimitExceeded
+	// is returned. Expired counters of the user and limit are dropped.
+	Increment(ctx context.Context, c *model.Counter, max int) error
+	// FindLast returns the counter of userID and name with the latest window,
+	// or ErrNotFound.
+	FindLast(ctx context.Context, userID int, name string) (*model.Counter, error)
+	// FindByUser returns the counters of userID whose window has not ended.
+	FindByUser(ctx context.Context, userID int) ([]*model.Counter, error)
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type PhotoStore interface {
+	Create(photo []byte, id int) error
+	FindById(id int) ([]byte, error)
+	DeleteByName(fileName string) error
+}
@@ -0,0 +1,69 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// blockedCondition formats the check that neither of the users in the two
+// columns has blocked the other.
+const blockedCondition = `NOT EXISTS (SELECT 1 FROM blocks
+	WHERE (blocks.user_id = %[1]s AND blocks.blocked_user = %[2]s)
+		OR (blocks.user_id = %[2]s AND blocks.blocked_user = %[1]s))`
+
+type BlockRepository struct {
+	s *Store
+}
+
+func (r *BlockRepository) Create(ctx context.Context, b *model.Block) error {
+	if b.UserID == b.BlockedUser {
+		return store.ErrSelfBlock
+	}
+
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	row := r.s.db.QueryRow(
+		ctx,
+		"INSERT INTO blocks(user_id, blocked_user, reason) VALUES ($1, $2, $3) RETURNING block_id, created_at",
+		b.UserID,
+		b.BlockedUser,
+		b.Reason,
+	)
+
+	return translateError(row.Scan(&b.ID, &b.CreatedAt))
+}
+
+func (r *BlockRepository) Exists(ctx context.Context, user1, user2 int) (bool, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := r.s.db.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM blocks
+			WHERE (user_id = $1 AND blocked_user = $2) OR (user_id = $2 AND blocked_user = $1))`,
+		user1, user2,
+	).Scan(&exists)
+
+	return exists, translateError(err)
+}
+
+func (r *BlockRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM blocks WHERE user_id = $1 OR blocked_user = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,132 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type CounterRepository struct {
+	s *Store
+}
+
+// Increment relies on the upsert taking the row lock, so concurrent
+// increments from any number of instances never count past max.
+func (r *CounterRepository) Increment(ctx context.Context, c *model.Counter, max int) error {
+	if max < 1 {
+		return store.ErrLimitExceeded
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	return r.s.inTx(ctx, func(tx *Store) error {
+		_, err := tx.db.Exec(
+			ctx,
+			"DELETE FROM counters WHERE user_id = $1 AND name = $2 AND window_end <= $3",
+			c.UserID, c.Name, c.WindowStart,
+		)
+		if err != nil {
+			return translateError(err)
+		}
+
+		err = tx.db.QueryRow(
+			ctx,
+			`INSERT INTO counters(user_id, name, window_start, window_end, count)
+				VALUES ($1, $2, $3, $4, 1)
+				ON CONFLICT (user_id, name, window_start) DO UPDATE SET count = counters.count + 1
+					WHERE counters.count < $5
+				RETURNING count`,
+			c.UserID, c.Name, c.WindowStart, c.WindowEnd, max,
+		).Scan(&c.Count)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.Count = max
+			return store.ErrLimitExceeded
+		}
+
+		return translateError(err)
+	})
+}
+
+func (r *CounterRepository) FindLast(ctx context.Context, userID int, name string) (*model.Counter, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	c := &model.Counter{}
+	err := r.s.db.QueryRow(
+		ctx,
+		`SELECT user_id, name, window_start, window_end, count FROM counters
+			WHERE user_id = $1 AND name = $2 ORDER BY window_end DESC LIMIT 1`,
+		userID, name,
+	).Scan(
+		&c.UserID,
+		&c.Name,
+		&c.WindowStart,
+		&c.WindowEnd,
+		&c.Count,
+	)
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return c, nil
+}
+
+func (r *CounterRepository) FindByUser(ctx context.Context, userID int) ([]*model.Counter, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		`SELECT user_id, name, window_start, window_end, count FROM counters
+			WHERE user_id = $1 AND window_end > $2 ORDER BY name, window_start`,
+		userID, time.Now(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counters := make([]*model.Counter, 0)
+	for rows.Next() {
+		c := &model.Counter{}
+		err := rows.Scan(
+			&c.UserID,
+			&c.Name,
+			&c.WindowStart,
+			&c.WindowEnd,
+			&c.Count,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		counters = append(counters, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counters, nil
+}
+
+func (r *CounterRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM counters WHERE user_id = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,56 @@
+package sqlstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterRepository_Increment(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	l := &model.Limit{Name: "burst", Max: 5, Period: time.Hour}
+	now := time.Now()
+
+	// Concurrent increments must not count past the limit.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.Counter().Increment(context.Background(), l.Counter(u, nil, now), l.Max)
+		}(i)
+	}
+	wg.Wait()
+
+	exceeded := 0
+	for _, err := range errs {
+		if err == store.ErrLimitExceeded {
+			exceeded++
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, 5, exceeded)
+
+	counters, err := s.Counter().FindByUser(context.Background(), u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, counters, 1) {
+		assert.Equal(t, 5, counters[0].Count)
+	}
+
+	assert.NoError(t, s.Counter().DeleteByUser(context.Background(), u.ID))
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u.ID)
+}
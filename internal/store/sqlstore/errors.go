@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// SQLSTATE codes of the violations the store cares about.
+const (
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+	codeStringTooLong       = "22001"
+)
+
+// translateError replaces pgx and constraint violation errors with the
+// store sentinels. Other errors are returned unchanged.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.ErrNotFound
+	}
+
+	pgErr := &pgconn.PgError{}
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		switch pgErr.ConstraintName {
+		case "users_login_key":
+			return store.ErrDuplicateLogin
+		case "users_phone_number_key":
+			return store.ErrDuplicatePhone
+		case "must_be_unique_pair":
+			return store.ErrAlreadyLiked
+		case "matches_user_1_user_2_key":
+			return store.ErrAlreadyMatched
+		case "passes_unique_pair":
+			return store.ErrAlreadyPassed
+		case "blocks_unique_pair":
+			return store.ErrAlreadyBlocked
+		}
+	case codeCheckViolation:
+		if pgErr.ConstraintName == "diff_users" {
+			switch pgErr.TableName {
+			case "matches":
+				return store.ErrSelfMatch
+			case "passes":
+				return store.ErrSelfPass
+			case "blocks":
+				return store.ErrSelfBlock
+			case "reports":
+				return store.ErrSelfReport
+			}
+			return store.ErrSelfLike
+		}
+		return store.ErrInvalidData
+	case codeNotNullViolation, codeStringTooLong:
+		return store.ErrInvalidData
+	case codeForeignKeyViolation:
+		return store.ErrNotFound
+	}
+
+	return err
+}
+
+// translateDeleteError is translateError for deletes, where a foreign key
+// violation means that the row is still referenced rather than that a row is
+// missing. Postgres names the referencing table in both cases, so only the
+// statement tells them apart.
+func translateDeleteError(err error) error {
+	pgErr := &pgconn.PgError{}
+	if errors.As(err, &pgErr) && pgErr.Code == codeForeignKeyViolation {
+		return store.ErrStillReferenced
+	}
+
+	return translateError(err)
+}
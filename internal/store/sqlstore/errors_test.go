@@ -0,0 +1,115 @@
+package sqlstore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	other := errors.New("other")
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "nil",
+			err:      nil,
+			expected: nil,
+		},
+		{
+			name:     "no rows",
+			err:      pgx.ErrNoRows,
+			expected: store.ErrNotFound,
+		},
+		{
+			name:     "duplicate login",
+			err:      &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "users_login_key"},
+			expected: store.ErrDuplicateLogin,
+		},
+		{
+			name:     "wrapped duplicate phone",
+			err:      fmt.Errorf("insert: %w", &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "users_phone_number_key"}),
+			expected: store.ErrDuplicatePhone,
+		},
+		{
+			name:     "duplicate like",
+			err:      &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "must_be_unique_pair"},
+			expected: store.ErrAlreadyLiked,
+		},
+		{
+			name:     "self like",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "likes", ConstraintName: "diff_users"},
+			expected: store.ErrSelfLike,
+		},
+		{
+			name:     "self match",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "matches", ConstraintName: "diff_users"},
+			expected: store.ErrSelfMatch,
+		},
+		{
+			name:     "duplicate pass",
+			err:      &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "passes_unique_pair"},
+			expected: store.ErrAlreadyPassed,
+		},
+		{
+			name:     "self pass",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "passes", ConstraintName: "diff_users"},
+			expected: store.ErrSelfPass,
+		},
+		{
+			name:     "duplicate block",
+			err:      &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "blocks_unique_pair"},
+			expected: store.ErrAlreadyBlocked,
+		},
+		{
+			name:     "self block",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "blocks", ConstraintName: "diff_users"},
+			expected: store.ErrSelfBlock,
+		},
+		{
+			name:     "self report",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "reports", ConstraintName: "diff_users"},
+			expected: store.ErrSelfReport,
+		},
+		{
+			name:     "age check",
+			err:      &pgconn.PgError{Code: codeCheckViolation, TableName: "users", ConstraintName: "users_age_check"},
+			expected: store.ErrInvalidData,
+		},
+		{
+			name:     "missing referenced user",
+			err:      &pgconn.PgError{Code: codeForeignKeyViolation, TableName: "likes"},
+			expected: store.ErrNotFound,
+		},
+		{
+			name:     "unknown",
+			err:      other,
+			expected: other,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, translateError(tc.err))
+		})
+	}
+}
+
+func TestTranslateDeleteError(t *testing.T) {
+	// Postgres names the referencing table whether the insert or the
+	// delete is the one that violates the key.
+	err := &pgconn.PgError{Code: codeForeignKeyViolation, TableName: "likes"}
+	assert.Equal(t, store.ErrNotFound, translateError(err))
+	assert.Equal(t, store.ErrStillReferenced, translateDeleteError(err))
+
+	assert.Equal(t, store.ErrNotFound, translateDeleteError(pgx.ErrNoRows))
+	assert.Nil(t, translateDeleteError(nil))
+}
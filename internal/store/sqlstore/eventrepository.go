@@ -0,0 +1,95 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+type EventRepository struct {
+	s *Store
+}
+
+// Create holds a lock on the row of the user until the transaction ends, so
+// the events of one user are committed in the order of their IDs and a
+// reader that has seen an ID never misses an earlier one.
+func (r *EventRepository) Create(ctx context.Context, e *model.Event) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	return r.s.inTx(ctx, func(tx *Store) error {
+		var userID int
+		err := tx.db.QueryRow(
+			ctx,
+			"SELECT user_id FROM users WHERE user_id = $1 FOR NO KEY UPDATE",
+			e.UserID,
+		).Scan(&userID)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		row := tx.db.QueryRow(
+			ctx,
+			"INSERT INTO events(user_id, type, data) VALUES ($1, $2, $3) RETURNING event_id, created_at",
+			e.UserID,
+			e.Type,
+			e.Data,
+		)
+
+		return translateError(row.Scan(&e.ID, &e.CreatedAt))
+	})
+}
+
+func (r *EventRepository) FindAfter(ctx context.Context, userID, afterID, limit int) ([]*model.Event, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM events WHERE user_id = $1 AND event_id > $2 ORDER BY event_id LIMIT $3",
+		userID, afterID, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*model.Event, 0)
+	for rows.Next() {
+		e := &model.Event{}
+		err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.Type,
+			&e.Data,
+			&e.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *EventRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM events WHERE user_id = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,77 @@
+package sqlstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventRepository_FindAfter(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	events := make([]*model.Event, 3)
+	for i := range events {
+		events[i] = &model.Event{UserID: u.ID, Type: model.EventLikeReceived, Data: json.RawMessage(`{"user_id":1}`)}
+		assert.NoError(t, s.Event().Create(context.Background(), events[i]))
+	}
+
+	found, err := s.Event().FindAfter(context.Background(), u.ID, events[0].ID, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, events[1].ID, found[0].ID)
+		assert.JSONEq(t, `{"user_id":1}`, string(found[0].Data))
+	}
+
+	assert.NoError(t, s.Event().DeleteByUser(context.Background(), u.ID))
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u.ID)
+}
+
+func TestEventRepository_CreateInOrder(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	ctx := context.Background()
+
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(ctx, u))
+
+	first := &model.Event{UserID: u.ID, Type: model.EventLikeReceived, Data: json.RawMessage(`{}`)}
+	second := &model.Event{UserID: u.ID, Type: model.EventLikeReceived, Data: json.RawMessage(`{}`)}
+
+	// An event of the user waits for the transaction that logged an earlier
+	// one, so it cannot be seen first.
+	done := make(chan error, 1)
+	err := s.WithTx(ctx, func(tx store.Store) error {
+		if err := tx.Event().Create(ctx, first); err != nil {
+			return err
+		}
+
+		go func() { done <- s.Event().Create(ctx, second) }()
+
+		select {
+		case err := <-done:
+			t.Error("event created before an earlier one was committed")
+			done <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, <-done)
+	assert.Greater(t, second.ID, first.ID)
+
+	assert.NoError(t, s.Event().DeleteByUser(ctx, u.ID))
+	db.Exec(ctx, "DELETE FROM users WHERE user_id = $1", u.ID)
+}
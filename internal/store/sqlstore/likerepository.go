@@ -0,0 +1,379 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type LikeRepository struct {
+	s *Store
+}
+
+func (r *LikeRepository) Create(ctx context.Context, l *model.Like) error {
+	if l.UserID == l.LikedUser {
+		return store.ErrSelfLike
+	}
+
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	return r.s.inTx(ctx, func(tx *Store) error {
+		return (&LikeRepository{tx}).insert(ctx, l)
+	})
+}
+
+// insert stores l, counting a super like against the quota first. It must
+// run in a transaction, so that the count goes back if the like fails.
+func (r *LikeRepository) insert(ctx context.Context, l *model.Like) error {
+	if l.Type == "" {
+		l.Type = model.LikeTypeRegular
+	}
+
+	if l.IsSuper() {
+		c, err := r.superLikeCounter(ctx, l.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = r.s.Counter().Increment(ctx, c, r.s.superLikesPerDay)
+		if err == store.ErrLimitExceeded {
+			return store.ErrSuperLikeQuota
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	row := r.s.db.QueryRow(
+		ctx,
+		"INSERT INTO likes(user_id, liked_user, type) VALUES ($1, $2, $3) RETURNING like_id, created_at",
+		l.UserID,
+		l.LikedUser,
+		l.Type,
+	)
+
+	return translateError(row.Scan(&l.ID, &l.CreatedAt))
+}
+
+// superLikeCounter returns the super like counter of userID for the current
+// day, which starts at midnight in their timezone.
+func (r *LikeRepository) superLikeCounter(ctx context.Context, userID int) (*model.Counter, error) {
+	u := &model.User{ID: userID}
+	err := r.s.db.QueryRow(ctx, "SELECT timezone FROM users WHERE user_id = $1", userID).Scan(&u.Timezone)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	prev, err := r.s.Counter().FindLast(ctx, userID, model.SuperLikeCounter)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	return model.SuperLikeLimit(r.s.superLikesPerDay).Counter(u, prev, time.Now()), nil
+}
+
+func (r *LikeRepository) SuperLikeQuota(ctx context.Context, userID int) (*model.Quota, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	c, err := r.superLikeCounter(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewQuota(r.s.superLikesPerDay, c.Count, c.WindowEnd), nil
+}
+
+// Swipe serializes swipes within a pair with a transaction-level advisory
+// lock, so of two users liking each other at the same time the second one
+// always sees the first like and creates the match.
+func (r *LikeRepository) Swipe(ctx context.Context, l *model.Like) (*model.Match, error) {
+	if l.UserID == l.LikedUser {
+		return nil, store.ErrSelfLike
+	}
+
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	m := &model.Match{
+		User1: l.UserID,
+		User2: l.LikedUser,
+	}
+	user1, user2 := m.Pair()
+
+	err := r.s.inTx(ctx, func(tx *Store) error {
+		if _, err := tx.db.Exec(ctx, "SELECT pg_advisory_xact_lock($1, $2)", user1, user2); err != nil {
+			return err
+		}
+
+		if err := (&LikeRepository{tx}).insert(ctx, l); err != nil {
+			return err
+		}
+
+		err := tx.db.QueryRow(
+			ctx,
+			`INSERT INTO matches(user_1, user_2)
+				SELECT $1, $2 WHERE EXISTS (SELECT 1 FROM likes WHERE user_id = $3 AND liked_user = $4)
+				ON CONFLICT DO NOTHING RETURNING match_id, created_at`,
+			user1, user2, l.LikedUser, l.UserID,
+		).Scan(&m.ID, &m.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			m = nil
+			return nil
+		}
+
+		return translateError(err)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (r *LikeRepository) FindMatchLike(ctx context.Context, l *model.Like) (*model.Like, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	like := &model.Like{}
+
+	err := r.s.db.QueryRow(
+		ctx,
+		"SELECT * FROM likes WHERE user_id = $1 AND liked_user = $2",
+		l.LikedUser, l.UserID,
+	).Scan(
+		&like.ID,
+		&like.UserID,
+		&like.LikedUser,
+		&like.Type,
+		&like.CreatedAt,
+	)
+
+	return like, translateError(err)
+}
+
+func (r *LikeRepository) FindLastByUser(ctx context.Context, userID int) (*model.Like, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM likes WHERE user_id = $1 ORDER BY created_at DESC, like_id DESC LIMIT 1",
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := scanLikes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(likes) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return likes[0], nil
+}
+
+func scanLikes(rows pgx.Rows) ([]*model.Like, error) {
+	defer rows.Close()
+
+	likes := make([]*model.Like, 0)
+	for rows.Next() {
+		l := &model.Like{}
+		err := rows.Scan(
+			&l.ID,
+			&l.UserID,
+			&l.LikedUser,
+			&l.Type,
+			&l.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		likes = append(likes, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return likes, nil
+}
+
+func (r *LikeRepository) find(ctx context.Context, field string, value interface{}) ([]*model.Like, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		fmt.Sprintf("SELECT * FROM likes WHERE %s = $1", field),
+		value,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := scanLikes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(likes) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return likes, nil
+}
+
+func (r *LikeRepository) findPage(ctx context.Context, field string, value interface{}, p *store.Pagination) (*store.LikePage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := p.PageLimit()
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		fmt.Sprintf(
+			"SELECT * FROM likes WHERE %s = $1 AND %s AND like_id > $2 ORDER BY like_id LIMIT $3",
+			field, fmt.Sprintf(blockedCondition, "likes.user_id", "likes.liked_user"),
+		),
+		value, afterID, limit+1,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := scanLikes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &store.LikePage{Likes: likes}
+	if len(likes) > limit {
+		page.Likes = likes[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: likes[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *LikeRepository) FindByUserID(ctx context.Context, userID int) ([]*model.Like, error) {
+	return r.find(ctx, "user_id", userID)
+}
+
+func (r *LikeRepository) FindByLikedUser(ctx context.Context, likedUser int) ([]*model.Like, error) {
+	return r.find(ctx, "liked_user", likedUser)
+}
+
+func (r *LikeRepository) FindLikers(ctx context.Context, likedUser int, userIDs []int) ([]int, error) {
+	ids := make([]int, 0)
+	if len(userIDs) == 0 {
+		return ids, nil
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT user_id FROM likes WHERE liked_user = $1 AND user_id = ANY($2)",
+		likedUser, userIDs,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *LikeRepository) FindPageByUserID(ctx context.Context, userID int, p *store.Pagination) (*store.LikePage, error) {
+	return r.findPage(ctx, "user_id", userID, p)
+}
+
+func (r *LikeRepository) FindPageByLikedUser(ctx context.Context, likedUser int, p *store.Pagination) (*store.LikePage, error) {
+	return r.findPage(ctx, "liked_user", likedUser, p)
+}
+
+func (r *LikeRepository) DeleteByUsers(ctx context.Context, userId, likedUser int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM likes WHERE user_id = $1 AND liked_user = $2",
+		userId, likedUser,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *LikeRepository) delete(ctx context.Context, field string, value interface{}) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		fmt.Sprintf("DELETE FROM likes WHERE %s = $1", field),
+		value,
+	)
+
+	return translateError(err)
+}
+
+func (r *LikeRepository) DeleteByUser(ctx context.Context, userID int) error {
+	return r.delete(ctx, "user_id", userID)
+}
+
+func (r *LikeRepository) DeleteByLikedUser(ctx context.Context, likedUser int) error {
+	return r.delete(ctx, "liked_user", likedUser)
+}
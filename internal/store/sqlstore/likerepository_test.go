@@ -0,0 +1,144 @@
+package sqlstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLikeRepository_Create(t *testing.T) {
+	l := testLike(t)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+
+	db.Exec(context.Background(), "DELETE FROM likes WHERE like_id = $1", l.ID)
+	deleteUsers(t, l.UserID, l.LikedUser)
+}
+
+func TestLikeRepository_DiffUsersConstraint(t *testing.T) {
+	u := testUser(t)
+	err := u.EncryptPassword()
+	assert.NoError(t, err)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	err = db.QueryRow(
+		context.Background(),
+		"INSERT INTO users(login, encrypted_password, name, age, gender, city, phone_number, about) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING user_id",
+		u.Login,
+		u.EncryptedPassword,
+		u.Name,
+		u.Age,
+		u.Gender,
+		u.City,
+		u.PhoneNumber,
+		u.About,
+	).Scan(&u.ID)
+
+	assert.NoError(t, err)
+
+	l := &model.Like{
+		UserID:    u.ID,
+		LikedUser: u.ID,
+	}
+
+	assert.Error(t, s.Like().Create(context.Background(), l))
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u.ID)
+}
+
+func TestLikeRepository_MustBeUniquePairConstraint(t *testing.T) {
+	l1 := testLike(t)
+	l2 := &model.Like{
+		UserID:    l1.UserID,
+		LikedUser: l1.LikedUser,
+	}
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	err := db.QueryRow(
+		context.Background(),
+		"INSERT INTO likes(user_id, liked_user) VALUES ($1, $2) RETURNING like_id",
+		l1.UserID,
+		l1.LikedUser,
+	).Scan(&l1.ID)
+	assert.NoError(t, err)
+
+	assert.Error(t, s.Like().Create(context.Background(), l2))
+
+	db.Exec(context.Background(), "DELETE FROM likes WHERE like_id = $1", l1.ID)
+	deleteUsers(t, l1.UserID, l1.LikedUser)
+}
+
+func TestLikeRepository_Swipe(t *testing.T) {
+	l := testLike(t)
+	back := &model.Like{
+		UserID:    l.LikedUser,
+		LikedUser: l.UserID,
+	}
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	// Both users swipe at once: exactly one of the swipes must match.
+	var wg sync.WaitGroup
+	results := make([]*model.Match, 2)
+	errs := make([]error, 2)
+	for i, like := range []*model.Like{l, back} {
+		wg.Add(1)
+		go func(i int, like *model.Like) {
+			defer wg.Done()
+			results[i], errs[i] = s.Like().Swipe(context.Background(), like)
+		}(i, like)
+	}
+	wg.Wait()
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.True(t, (results[0] == nil) != (results[1] == nil))
+
+	matches, err := s.Match().FindByUser(context.Background(), l.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	db.Exec(context.Background(), "DELETE FROM matches WHERE user_1 = $1 OR user_2 = $1", l.UserID)
+	db.Exec(context.Background(), "DELETE FROM likes WHERE user_id = $1 OR liked_user = $1", l.UserID)
+	deleteUsers(t, l.UserID, l.LikedUser)
+}
+
+func TestLikeRepository_SuperLikeWithdrawn(t *testing.T) {
+	l := testLike(t)
+	l.Type = model.LikeTypeSuper
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	s.SetSuperLikesPerDay(1)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Like().Create(ctx, l))
+	assert.NoError(t, s.Like().DeleteByUsers(ctx, l.UserID, l.LikedUser))
+
+	// Withdrawing the super like does not give the quota back.
+	q, err := s.Like().SuperLikeQuota(ctx, l.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Remaining)
+	assert.ErrorIs(t, s.Like().Create(ctx, &model.Like{UserID: l.UserID, LikedUser: l.LikedUser, Type: model.LikeTypeSuper}), store.ErrSuperLikeQuota)
+
+	db.Exec(ctx, "DELETE FROM counters WHERE user_id = $1", l.UserID)
+	deleteUsers(t, l.UserID, l.LikedUser)
+}
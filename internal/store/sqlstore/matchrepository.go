@@ -0,0 +1,187 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// matchNotBlocked leaves out the matches whose users have blocked each other.
+var matchNotBlocked = fmt.Sprintf(blockedCondition, "matches.user_1", "matches.user_2")
+
+type MatchRepository struct {
+	s *Store
+}
+
+func (r *MatchRepository) Create(ctx context.Context, m *model.Match) error {
+	if m.User1 == m.User2 {
+		return store.ErrSelfMatch
+	}
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	user1, user2 := m.Pair()
+	row := r.s.db.QueryRow(
+		ctx,
+		"INSERT INTO matches(user_1, user_2) VALUES ($1, $2) RETURNING match_id, created_at",
+		user1,
+		user2,
+	)
+
+	return translateError(row.Scan(&m.ID, &m.CreatedAt))
+}
+
+// scanMatches reads matches so that User1 is always userID.
+func scanMatches(rows pgx.Rows, userID int) ([]*model.Match, error) {
+	defer rows.Close()
+
+	matches := make([]*model.Match, 0)
+	for rows.Next() {
+		m := &model.Match{}
+		err := rows.Scan(
+			&m.ID,
+			&m.User1,
+			&m.User2,
+			&m.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if m.User1 != userID {
+			m.User1, m.User2 = m.User2, m.User1
+		}
+
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+func (r *MatchRepository) FindByUser(ctx context.Context, userID int) ([]*model.Match, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM matches WHERE user_1 = $1 OR user_2 = $1",
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMatches(rows, userID)
+}
+
+// FindByUsers returns the match of the pair with User1 set to user1.
+func (r *MatchRepository) FindByUsers(ctx context.Context, user1, user2 int) (*model.Match, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	first, second := (&model.Match{User1: user1, User2: user2}).Pair()
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM matches WHERE user_1 = $1 AND user_2 = $2",
+		first, second,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := scanMatches(rows, user1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return matches[0], nil
+}
+
+func (r *MatchRepository) FindPageByUser(ctx context.Context, userID int, p *store.Pagination) (*store.MatchPage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := p.PageLimit()
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM matches WHERE (user_1 = $1 OR user_2 = $1) AND "+matchNotBlocked+" AND match_id > $2 ORDER BY match_id LIMIT $3",
+		userID, afterID, limit+1,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := scanMatches(rows, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &store.MatchPage{Matches: matches}
+	if len(matches) > limit {
+		page.Matches = matches[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: matches[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *MatchRepository) DeleteByUsers(ctx context.Context, user1, user2 int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	user1, user2 = (&model.Match{User1: user1, User2: user2}).Pair()
+	tag, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM matches WHERE user_1 = $1 AND user_2 = $2",
+		user1, user2,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *MatchRepository) DeleteByUser(ctx context.Context, id int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM matches WHERE user_1 = $1 OR user_2 = $1",
+		id,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,205 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type MessageRepository struct {
+	s *Store
+}
+
+// Create takes the recipient from the match itself and holds a share lock on
+// it, so a message cannot slip into a thread that is being unmatched.
+func (r *MessageRepository) Create(ctx context.Context, m *model.Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	err := r.s.db.QueryRow(
+		ctx,
+		`INSERT INTO messages(match_id, sender, recipient, body)
+			SELECT match_id, $2::integer, CASE WHEN user_1 = $2 THEN user_2 ELSE user_1 END, $3
+			FROM matches WHERE match_id = $1 AND $2 IN (user_1, user_2)
+			FOR SHARE
+		RETURNING message_id, recipient, created_at`,
+		m.MatchID,
+		m.Sender,
+		m.Body,
+	).Scan(&m.ID, &m.Recipient, &m.CreatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.ErrThreadLocked
+	}
+
+	return translateError(err)
+}
+
+func (r *MessageRepository) FindThread(ctx context.Context, matchID int) (*model.Thread, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	t := &model.Thread{}
+	err := r.s.db.QueryRow(
+		ctx,
+		`SELECT match_id, user_1, user_2, false FROM matches WHERE match_id = $1
+		UNION ALL
+		(SELECT match_id, LEAST(sender, recipient), GREATEST(sender, recipient), true
+			FROM messages WHERE match_id = $1 LIMIT 1)
+		ORDER BY 4 LIMIT 1`,
+		matchID,
+	).Scan(&t.MatchID, &t.User1, &t.User2, &t.Locked)
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return t, nil
+}
+
+func scanMessages(rows pgx.Rows) ([]*model.Message, error) {
+	defer rows.Close()
+
+	messages := make([]*model.Message, 0)
+	for rows.Next() {
+		m := &model.Message{}
+		err := rows.Scan(
+			&m.ID,
+			&m.MatchID,
+			&m.Sender,
+			&m.Recipient,
+			&m.Body,
+			&m.Edited,
+			&m.Deleted,
+			&m.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *MessageRepository) FindPageByMatch(ctx context.Context, matchID int, p *store.Pagination) (*store.MessagePage, error) {
+	beforeID, err := p.BeforeID()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := p.PageLimit()
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM messages WHERE match_id = $1 AND ($2 = 0 OR message_id < $2) ORDER BY message_id DESC LIMIT $3",
+		matchID, beforeID, limit+1,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &store.MessagePage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: messages[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+// change applies set to the live message of m.Sender with m.ID in m.MatchID
+// and fills m in from the result. Like Create, it holds a share lock on the
+// match, so a message cannot be changed in a thread that is being unmatched.
+func (r *MessageRepository) change(ctx context.Context, m *model.Message, set string, args ...any) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	return r.s.inTx(ctx, func(tx *Store) error {
+		var matchID int
+		err := tx.db.QueryRow(
+			ctx,
+			"SELECT match_id FROM matches WHERE match_id = $1 AND $2 IN (user_1, user_2) FOR SHARE",
+			m.MatchID,
+			m.Sender,
+		).Scan(&matchID)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrThreadLocked
+		}
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		rows, err := tx.db.Query(
+			ctx,
+			"UPDATE messages SET "+set+" WHERE message_id = $1 AND match_id = $2 AND sender = $3 AND NOT deleted RETURNING *",
+			append([]any{m.ID, m.MatchID, m.Sender}, args...)...,
+		)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		messages, err := scanMessages(rows)
+		if err != nil {
+			return translateError(err)
+		}
+
+		if len(messages) == 0 {
+			return store.ErrNotFound
+		}
+
+		*m = *messages[0]
+
+		return nil
+	})
+}
+
+func (r *MessageRepository) Update(ctx context.Context, m *model.Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	return r.change(ctx, m, "body = $4, edited = true", m.Body)
+}
+
+func (r *MessageRepository) Delete(ctx context.Context, m *model.Message) error {
+	return r.change(ctx, m, "body = '', deleted = true")
+}
+
+func (r *MessageRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM messages WHERE sender = $1 OR recipient = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,55 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRepository_Thread(t *testing.T) {
+	l := testLike(t)
+	defer deleteUsers(t, l.UserID, l.LikedUser)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	ctx := context.Background()
+
+	match := &model.Match{User1: l.UserID, User2: l.LikedUser}
+	assert.NoError(t, s.Match().Create(ctx, match))
+
+	m := &model.Message{MatchID: match.ID, Sender: l.LikedUser, Body: "hi"}
+	assert.NoError(t, s.Message().Create(ctx, m))
+	assert.Equal(t, l.UserID, m.Recipient)
+
+	m.Body = "hello"
+	assert.NoError(t, s.Message().Update(ctx, m))
+	assert.True(t, m.Edited)
+
+	other := &model.Message{ID: m.ID, MatchID: match.ID, Sender: l.UserID, Body: "mine"}
+	assert.ErrorIs(t, s.Message().Update(ctx, other), store.ErrNotFound)
+
+	page, err := s.Message().FindPageByMatch(ctx, match.ID, &store.Pagination{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Messages, 1) {
+		assert.Equal(t, "hello", page.Messages[0].Body)
+	}
+
+	assert.NoError(t, s.Match().DeleteByUsers(ctx, l.UserID, l.LikedUser))
+
+	th, err := s.Message().FindThread(ctx, match.ID)
+	assert.NoError(t, err)
+	assert.True(t, th.Locked)
+	assert.True(t, th.Has(l.UserID))
+	assert.ErrorIs(t, s.Message().Create(ctx, &model.Message{MatchID: match.ID, Sender: l.UserID, Body: "hi"}), store.ErrThreadLocked)
+	assert.ErrorIs(t, s.Message().Update(ctx, m), store.ErrThreadLocked)
+	assert.ErrorIs(t, s.Message().Delete(ctx, m), store.ErrThreadLocked)
+
+	assert.NoError(t, s.Message().DeleteByUser(ctx, l.UserID))
+	_, err = s.Message().FindThread(ctx, match.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
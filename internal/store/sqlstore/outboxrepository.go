@@ -0,0 +1,186 @@
+package sqlstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type OutboxRepository struct {
+	s *Store
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, m *model.OutboxMessage) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	row := r.s.db.QueryRow(
+		ctx,
+		`INSERT INTO outbox(type, payload) VALUES ($1, $2)
+		RETURNING outbox_id, status, attempts, next_attempt_at, last_error, created_at`,
+		m.Type,
+		m.Payload,
+	)
+
+	return translateError(row.Scan(&m.ID, &m.Status, &m.Attempts, &m.NextAttemptAt, &m.LastError, &m.CreatedAt))
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	// SKIP LOCKED lets dispatchers of other instances claim the next
+	// messages instead of waiting for these.
+	rows, err := r.s.db.Query(
+		ctx,
+		`UPDATE outbox SET next_attempt_at = $1::timestamptz + $2::interval
+		WHERE outbox_id IN (
+			SELECT outbox_id FROM outbox
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY outbox_id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now, lease, model.OutboxPending, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := scanOutboxMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+
+	return messages, nil
+}
+
+func (r *OutboxRepository) FindByStatus(ctx context.Context, status string, afterID, limit int) ([]*model.OutboxMessage, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM outbox WHERE status = $1 AND outbox_id > $2 ORDER BY outbox_id LIMIT $3",
+		status, afterID, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxMessages(rows)
+}
+
+func (r *OutboxRepository) Update(ctx context.Context, m *model.OutboxMessage) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"UPDATE outbox SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5 WHERE outbox_id = $1",
+		m.ID,
+		m.Status,
+		m.Attempts,
+		m.NextAttemptAt,
+		m.LastError,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) Replay(ctx context.Context, id int, now time.Time) (*model.OutboxMessage, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"UPDATE outbox SET status = $2, attempts = 0, next_attempt_at = $3 WHERE outbox_id = $1 AND status = $4 RETURNING *",
+		id, model.OutboxPending, now, model.OutboxDead,
+	)
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	messages, err := scanOutboxMessages(rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if len(messages) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return messages[0], nil
+}
+
+func (r *OutboxRepository) DeleteByID(ctx context.Context, id int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM outbox WHERE outbox_id = $1",
+		id,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanOutboxMessages(rows pgx.Rows) ([]*model.OutboxMessage, error) {
+	defer rows.Close()
+
+	messages := make([]*model.OutboxMessage, 0)
+	for rows.Next() {
+		m := &model.OutboxMessage{}
+		err := rows.Scan(
+			&m.ID,
+			&m.Type,
+			&m.Payload,
+			&m.Status,
+			&m.Attempts,
+			&m.NextAttemptAt,
+			&m.LastError,
+			&m.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
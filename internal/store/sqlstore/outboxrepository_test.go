@@ -0,0 +1,70 @@
+package sqlstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRepository_Claim(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	ctx := context.Background()
+
+	m := &model.OutboxMessage{Type: model.DomainUserDeleted, Payload: json.RawMessage(`{"user_id":1}`)}
+	assert.NoError(t, s.Outbox().Create(ctx, m))
+	assert.Equal(t, model.OutboxPending, m.Status)
+	defer s.Outbox().DeleteByID(ctx, m.ID)
+
+	claimed := func(now time.Time) bool {
+		messages, err := s.Outbox().Claim(ctx, now, time.Minute, 1000)
+		assert.NoError(t, err)
+		for _, v := range messages {
+			if v.ID == m.ID {
+				assert.JSONEq(t, `{"user_id":1}`, string(v.Payload))
+				return true
+			}
+		}
+		return false
+	}
+
+	now := time.Now().Add(time.Second)
+	assert.True(t, claimed(now))
+	assert.False(t, claimed(now))
+
+	m.Status = model.OutboxDead
+	m.Attempts = 1
+	m.LastError = "boom"
+	assert.NoError(t, s.Outbox().Update(ctx, m))
+	assert.False(t, claimed(now.Add(time.Hour)))
+
+	dead, err := s.Outbox().FindByStatus(ctx, model.OutboxDead, 0, 1000)
+	assert.NoError(t, err)
+	found := false
+	for _, v := range dead {
+		if v.ID == m.ID {
+			found = true
+			assert.Equal(t, "boom", v.LastError)
+		}
+	}
+	assert.True(t, found)
+
+	// A replayed message is pending again with all of its attempts.
+	replayed, err := s.Outbox().Replay(ctx, m.ID, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, model.OutboxPending, replayed.Status)
+	assert.Zero(t, replayed.Attempts)
+	assert.True(t, claimed(now.Add(time.Hour)))
+	_, err = s.Outbox().Replay(ctx, m.ID, now)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	assert.NoError(t, s.Outbox().DeleteByID(ctx, m.ID))
+	assert.ErrorIs(t, s.Outbox().DeleteByID(ctx, m.ID), store.ErrNotFound)
+}
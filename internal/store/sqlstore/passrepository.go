@@ -0,0 +1,92 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type PassRepository struct {
+	s *Store
+}
+
+func (r *PassRepository) Create(ctx context.Context, p *model.Pass) error {
+	if p.UserID == p.PassedUser {
+		return store.ErrSelfPass
+	}
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	row := r.s.db.QueryRow(
+		ctx,
+		"INSERT INTO passes(user_id, passed_user) VALUES ($1, $2) RETURNING pass_id, created_at",
+		p.UserID,
+		p.PassedUser,
+	)
+
+	return translateError(row.Scan(&p.ID, &p.CreatedAt))
+}
+
+func (r *PassRepository) FindLastByUser(ctx context.Context, userID int) (*model.Pass, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	p := &model.Pass{}
+	err := r.s.db.QueryRow(
+		ctx,
+		`SELECT pass_id, user_id, passed_user, created_at FROM passes
+			WHERE user_id = $1 ORDER BY created_at DESC, pass_id DESC LIMIT 1`,
+		userID,
+	).Scan(
+		&p.ID,
+		&p.UserID,
+		&p.PassedUser,
+		&p.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return p, nil
+}
+
+func (r *PassRepository) DeleteByID(ctx context.Context, id int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM passes WHERE pass_id = $1",
+		id,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *PassRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM passes WHERE user_id = $1 OR passed_user = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,51 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnIdleTime   time.Duration
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+func NewPoolConfig() *PoolConfig {
+	return &PoolConfig{
+		MaxConns:          10,
+		MinConns:          2,
+		MaxConnIdleTime:   5 * time.Minute,
+		MaxConnLifetime:   time.Hour,
+		HealthCheckPeriod: time.Minute,
+	}
+}
+
+func NewPool(ctx context.Context, databaseURL string, c *PoolConfig) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxConns = c.MaxConns
+	config.MinConns = c.MinConns
+	config.MaxConnIdleTime = c.MaxConnIdleTime
+	config.MaxConnLifetime = c.MaxConnLifetime
+	config.HealthCheckPeriod = c.HealthCheckPeriod
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+type PreferencesRepository struct {
+	s *Store
+}
+
+func (r *PreferencesRepository) FindByUser(ctx context.Context, userID int) (*model.Preferences, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	p := &model.Preferences{UserID: userID}
+	err := r.s.db.QueryRow(
+		ctx,
+		"SELECT genders, min_age, max_age, cities, max_distance, mutual FROM preferences WHERE user_id = $1",
+		userID,
+	).Scan(
+		&p.Genders,
+		&p.MinAge,
+		&p.MaxAge,
+		&p.Cities,
+		&p.MaxDistance,
+		&p.Mutual,
+	)
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return p, nil
+}
+
+// Save creates or replaces the preferences of p.UserID.
+func (r *PreferencesRepository) Save(ctx context.Context, p *model.Preferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	// pgx sends nil slices as NULL.
+	if p.Genders == nil {
+		p.Genders = make([]string, 0)
+	}
+	if p.Cities == nil {
+		p.Cities = make([]string, 0)
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		`INSERT INTO preferences(user_id, genders, min_age, max_age, cities, max_distance, mutual)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (user_id) DO UPDATE SET
+				genders = EXCLUDED.genders,
+				min_age = EXCLUDED.min_age,
+				max_age = EXCLUDED.max_age,
+				cities = EXCLUDED.cities,
+				max_distance = EXCLUDED.max_distance,
+				mutual = EXCLUDED.mutual`,
+		p.UserID, p.Genders, p.MinAge, p.MaxAge, p.Cities, p.MaxDistance, p.Mutual,
+	)
+
+	return translateError(err)
+}
+
+func (r *PreferencesRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM preferences WHERE user_id = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
@@ -0,0 +1,36 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferencesRepository_Save(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	_, err := s.Preferences().FindByUser(context.Background(), u.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	p := &model.Preferences{UserID: u.ID, MinAge: 20}
+	assert.NoError(t, s.Preferences().Save(context.Background(), p))
+
+	p.Genders = []string{"female"}
+	assert.NoError(t, s.Preferences().Save(context.Background(), p))
+
+	found, err := s.Preferences().FindByUser(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, p, found)
+
+	assert.NoError(t, s.Preferences().DeleteByUser(context.Background(), u.ID))
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u.ID)
+}
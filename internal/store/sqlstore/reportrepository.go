@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type ReportRepository struct {
+	s *Store
+}
+
+// Create puts rep into the moderation queue as a pending report. Reports
+// outlive the reported user, so there is no foreign key to check that the
+// user exists, and the insert checks it itself.
+func (r *ReportRepository) Create(ctx context.Context, rep *model.Report) error {
+	if rep.UserID == rep.ReportedUser {
+		return store.ErrSelfReport
+	}
+
+	if err := rep.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rep.Status = model.ReportStatusPending
+	row := r.s.db.QueryRow(
+		ctx,
+		`INSERT INTO reports(user_id, reported_user, reason, comment, status)
+			SELECT $1, $2, $3, $4, $5
+			WHERE EXISTS (SELECT 1 FROM users WHERE user_id = $2)
+		RETURNING report_id, created_at`,
+		rep.UserID,
+		rep.ReportedUser,
+		rep.Reason,
+		rep.Comment,
+		rep.Status,
+	)
+
+	return translateError(row.Scan(&rep.ID, &rep.CreatedAt))
+}
+
+func (r *ReportRepository) FindByReportedUser(ctx context.Context, reportedUser int) ([]*model.Report, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM reports WHERE reported_user = $1 ORDER BY report_id",
+		reportedUser,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*model.Report, 0)
+	for rows.Next() {
+		rep := &model.Report{}
+		err := rows.Scan(
+			&rep.ID,
+			&rep.UserID,
+			&rep.ReportedUser,
+			&rep.Reason,
+			&rep.Comment,
+			&rep.Status,
+			&rep.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, rep)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+func (r *ReportRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM reports WHERE user_id = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
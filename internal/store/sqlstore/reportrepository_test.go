@@ -0,0 +1,40 @@
+package sqlstore_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportRepository_Create(t *testing.T) {
+	l := testLike(t)
+	defer deleteUsers(t, l.UserID, l.LikedUser)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	ctx := context.Background()
+
+	// There is no foreign key on the reported user, so the check is made by
+	// Create itself.
+	rep := &model.Report{UserID: l.UserID, ReportedUser: math.MaxInt32, Reason: model.ReportReasonSpam}
+	assert.ErrorIs(t, s.Report().Create(ctx, rep), store.ErrNotFound)
+
+	rep.ReportedUser = l.LikedUser
+	assert.NoError(t, s.Report().Create(ctx, rep))
+	assert.NotZero(t, rep.ID)
+	assert.Equal(t, model.ReportStatusPending, rep.Status)
+
+	reports, err := s.Report().FindByReportedUser(ctx, l.LikedUser)
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, rep.ID, reports[0].ID)
+	}
+
+	assert.NoError(t, s.Report().DeleteByUser(ctx, l.UserID))
+}
@@ -0,0 +1,151 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx, so repositories
+// work the same way inside and outside of a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Store struct {
+	db                    querier
+	queryTimeout          time.Duration
+	superLikesPerDay      int
+	userRepository        *UserRepository
+	likeRepository        *LikeRepository
+	matchRepository       *MatchRepository
+	passRepository        *PassRepository
+	preferencesRepository *PreferencesRepository
+	unmatchRepository     *UnmatchRepository
+	counterRepository     *CounterRepository
+	blockRepository       *BlockRepository
+	reportRepository      *ReportRepository
+	messageRepository     *MessageRepository
+	eventRepository       *EventRepository
+	outboxRepository      *OutboxRepository
+}
+
+// New returns a Store that bounds every query by queryTimeout.
+// A zero queryTimeout leaves deadlines to the caller's context.
+func New(db *pgxpool.Pool, queryTimeout time.Duration) *Store {
+	return newStore(db, queryTimeout, store.DefaultSuperLikesPerDay)
+}
+
+// newStore builds all repositories up front, so that concurrent requests
+// only ever read the fields of s.
+func newStore(db querier, queryTimeout time.Duration, superLikesPerDay int) *Store {
+	s := &Store{
+		db:               db,
+		queryTimeout:     queryTimeout,
+		superLikesPerDay: superLikesPerDay,
+	}
+
+	s.userRepository = &UserRepository{s}
+	s.likeRepository = &LikeRepository{s}
+	s.matchRepository = &MatchRepository{s}
+	s.passRepository = &PassRepository{s}
+	s.preferencesRepository = &PreferencesRepository{s}
+	s.unmatchRepository = &UnmatchRepository{s}
+	s.counterRepository = &CounterRepository{s}
+	s.blockRepository = &BlockRepository{s}
+	s.reportRepository = &ReportRepository{s}
+	s.messageRepository = &MessageRepository{s}
+	s.eventRepository = &EventRepository{s}
+	s.outboxRepository = &OutboxRepository{s}
+
+	return s
+}
+
+// SetSuperLikesPerDay changes the daily super like quota of every user.
+func (s *Store) SetSuperLikesPerDay(n int) {
+	s.superLikesPerDay = n
+}
+
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// WithTx begins a transaction, or a savepoint when s is already
+// transactional, and hands fn a Store bound to it.
+func (s *Store) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	return s.inTx(ctx, func(tx *Store) error {
+		return fn(tx)
+	})
+}
+
+func (s *Store) inTx(ctx context.Context, fn func(tx *Store) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(newStore(tx, s.queryTimeout, s.superLikesPerDay)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Store) User() store.UserRepository {
+	return s.userRepository
+}
+
+func (s *Store) Like() store.LikeRepository {
+	return s.likeRepository
+}
+
+func (s *Store) Match() store.MatchRepository {
+	return s.matchRepository
+}
+
+func (s *Store) Pass() store.PassRepository {
+	return s.passRepository
+}
+
+func (s *Store) Preferences() store.PreferencesRepository {
+	return s.preferencesRepository
+}
+
+func (s *Store) Unmatch() store.UnmatchRepository {
+	return s.unmatchRepository
+}
+
+func (s *Store) Counter() store.CounterRepository {
+	return s.counterRepository
+}
+
+func (s *Store) Block() store.BlockRepository {
+	return s.blockRepository
+}
+
+func (s *Store) Report() store.ReportRepository {
+	return s.reportRepository
+}
+
+func (s *Store) Message() store.MessageRepository {
+	return s.messageRepository
+}
+
+func (s *Store) Event() store.EventRepository {
+	return s.eventRepository
+}
+
+func (s *Store) Outbox() store.OutboxRepository {
+	return s.outboxRepository
+}
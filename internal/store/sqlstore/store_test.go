@@ -0,0 +1,32 @@
+package sqlstore_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Repositories(t *testing.T) {
+	s := sqlstore.New(nil, 0)
+
+	// Handlers share the store, so the repositories are read concurrently.
+	repositories := make([]store.UserRepository, 8)
+	var wg sync.WaitGroup
+	for i := range repositories {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repositories[i] = s.User()
+			s.Like()
+			s.Outbox()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range repositories {
+		assert.Same(t, repositories[0], r)
+	}
+}
@@ -0,0 +1,84 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+)
+
+type UnmatchRepository struct {
+	s *Store
+}
+
+func (r *UnmatchRepository) Create(ctx context.Context, u *model.Unmatch) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	row := r.s.db.QueryRow(
+		ctx,
+		"INSERT INTO unmatches(user_id, unmatched_user, reason) VALUES ($1, $2, $3) RETURNING unmatch_id, created_at",
+		u.UserID,
+		u.UnmatchedUser,
+		u.Reason,
+	)
+
+	return translateError(row.Scan(&u.ID, &u.CreatedAt))
+}
+
+// FindByUser returns the unmatches on either side of userID, oldest first.
+func (r *UnmatchRepository) FindByUser(ctx context.Context, userID int) ([]*model.Unmatch, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM unmatches WHERE user_id = $1 OR unmatched_user = $1 ORDER BY unmatch_id",
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unmatches := make([]*model.Unmatch, 0)
+	for rows.Next() {
+		u := &model.Unmatch{}
+		err := rows.Scan(
+			&u.ID,
+			&u.UserID,
+			&u.UnmatchedUser,
+			&u.Reason,
+			&u.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		unmatches = append(unmatches, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return unmatches, nil
+}
+
+func (r *UnmatchRepository) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.s.db.Exec(
+		ctx,
+		"DELETE FROM unmatches WHERE user_id = $1 OR unmatched_user = $1",
+		userID,
+	)
+
+	return translateError(err)
+}
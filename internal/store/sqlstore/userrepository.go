@@ -0,0 +1,539 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// defaultTimezone is the timezone of users who have not set one.
+const defaultTimezone = "UTC"
+
+type UserRepository struct {
+	s *Store
+}
+
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	if err := u.EncryptPassword(); err != nil {
+		return err
+	}
+
+	// pgx sends nil slices as NULL.
+	if u.Interests == nil {
+		u.Interests = make([]string, 0)
+	}
+
+	if u.Timezone == "" {
+		u.Timezone = defaultTimezone
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	row := r.s.db.QueryRow(
+		ctx,
+		`INSERT INTO users(login, encrypted_password, name, age, gender, city, phone_number, about, interests, timezone) 
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING user_id`,
+		u.Login, u.EncryptedPassword, u.Name, u.Age, u.Gender, u.City, u.PhoneNumber, u.About, u.Interests, u.Timezone,
+	)
+
+	return translateError(row.Scan(&u.ID))
+}
+
+func scanUsers(rows pgx.Rows) ([]*model.User, error) {
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		u := &model.User{}
+		var latitude, longitude *float64
+		var lastSeenAt *time.Time
+		err := rows.Scan(
+			&u.ID,
+			&u.Login,
+			&u.EncryptedPassword,
+			&u.Name,
+			&u.Age,
+			&u.Gender,
+			&u.City,
+			&u.PhoneNumber,
+			&u.About,
+			&latitude,
+			&longitude,
+			&u.Interests,
+			&lastSeenAt,
+			&u.Timezone,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if latitude != nil && longitude != nil {
+			u.Location = &model.Location{
+				Latitude:  *latitude,
+				Longitude: *longitude,
+			}
+		}
+
+		if lastSeenAt != nil {
+			u.LastSeenAt = *lastSeenAt
+		}
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) All(ctx context.Context, currentUserID int) ([]*model.User, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM users WHERE user_id != $1 AND "+notBlockedByCurrent,
+		currentUserID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUsers(rows)
+}
+
+func (r *UserRepository) AllPage(ctx context.Context, currentUserID int, p *store.Pagination) (*store.UserPage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := p.PageLimit()
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM users WHERE user_id != $1 AND "+notBlockedByCurrent+" AND user_id > $2 ORDER BY user_id LIMIT $3",
+		currentUserID, afterID, limit+1,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &store.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: users[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *UserRepository) FindByFilters(ctx context.Context, currentUserID int, f *store.UserFilter) (*store.UserPage, error) {
+	return r.findByFilters(ctx, currentUserID, f)
+}
+
+// notBlockedByCurrent leaves out the users $1 has blocked or is blocked by.
+var notBlockedByCurrent = fmt.Sprintf(blockedCondition, "$1", "users.user_id")
+
+// feedConditions leave out the users $1 has already swiped, matched or
+// unmatched, whichever of the two dissolved the match.
+var feedConditions = []string{
+	"NOT EXISTS (SELECT 1 FROM likes WHERE likes.user_id = $1 AND likes.liked_user = users.user_id)",
+	"NOT EXISTS (SELECT 1 FROM passes WHERE passes.user_id = $1 AND passes.passed_user = users.user_id)",
+	`NOT EXISTS (SELECT 1 FROM matches WHERE (matches.user_1 = $1 AND matches.user_2 = users.user_id)
+		OR (matches.user_2 = $1 AND matches.user_1 = users.user_id))`,
+	`NOT EXISTS (SELECT 1 FROM unmatches WHERE (unmatches.user_id = $1 AND unmatches.unmatched_user = users.user_id)
+		OR (unmatches.unmatched_user = $1 AND unmatches.user_id = users.user_id))`,
+}
+
+func (r *UserRepository) Feed(ctx context.Context, currentUserID int, f *store.UserFilter) (*store.UserPage, error) {
+	return r.findByFilters(ctx, currentUserID, f, feedConditions...)
+}
+
+// mutualCondition leaves out the users whose preferences $1 does not fit.
+// Users without stored preferences accept everyone.
+const mutualCondition = `NOT EXISTS (SELECT 1 FROM preferences p JOIN users me ON me.user_id = $1
+	WHERE p.user_id = users.user_id AND NOT (
+		(cardinality(p.genders) = 0 OR me.gender = ANY(p.genders))
+		AND (p.min_age = 0 OR me.age >= p.min_age)
+		AND (p.max_age = 0 OR me.age <= p.max_age)
+		AND (cardinality(p.cities) = 0 OR me.city = ANY(p.cities))))`
+
+// distanceCondition formats the haversine check from the placeholders of the
+// snapped origin latitude and longitude, of the radius in kilometers and of
+// the grid the stored location is snapped to, as model.Location.Snap does.
+const distanceCondition = `2 * 6371 * asin(least(1, sqrt(
+	power(sin(radians((floor(latitude / %[4]s) + 0.5) * %[4]s - %[1]s) / 2), 2) +
+	cos(radians(%[1]s)) * cos(radians((floor(latitude / %[4]s) + 0.5) * %[4]s)) *
+	power(sin(radians((floor(longitude / %[4]s) + 0.5) * %[4]s - %[2]s) / 2), 2)))) <= %[3]s`
+
+// lastSeenKey orders users by when they were last seen, with the ones never
+// seen at the zero time.Time they are scanned as.
+const lastSeenKey = "coalesce(last_seen_at, '0001-01-01 00:00:00+00'::timestamptz)"
+
+// findByFilters pages through the users matching f and the extra conditions,
+// which may refer to currentUserID as $1.
+func (r *UserRepository) findByFilters(ctx context.Context, currentUserID int, f *store.UserFilter, conditions ...string) (*store.UserPage, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{currentUserID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := append([]string{"user_id != $1", notBlockedByCurrent}, conditions...)
+	if f.MinAge != 0 {
+		where = append(where, "age >= "+arg(f.MinAge))
+	}
+	if f.MaxAge != 0 {
+		where = append(where, "age <= "+arg(f.MaxAge))
+	}
+	if f.Gender != "" {
+		where = append(where, "gender = "+arg(f.Gender))
+	}
+	if len(f.Genders) != 0 {
+		where = append(where, "gender = ANY("+arg(f.Genders)+")")
+	}
+	if len(f.Cities) != 0 {
+		where = append(where, "city = ANY("+arg(f.Cities)+")")
+	}
+	if f.HasAbout {
+		where = append(where, "about IS NOT NULL AND about != ''")
+	}
+	if f.Mutual {
+		where = append(where, mutualCondition)
+	}
+	if f.MaxDistance != 0 {
+		if f.Origin == nil {
+			return nil, store.ErrNoLocation
+		}
+
+		// A degree of latitude is the same length everywhere, so the
+		// bounding band lets the location index cut the candidates first.
+		// It is a cell wider, since the distance is between snapped
+		// locations.
+		origin := f.Origin.Snap()
+		band := float64(f.MaxDistance)/(model.EarthRadius*math.Pi/180) + model.LocationGrid
+		where = append(where, fmt.Sprintf(
+			"latitude BETWEEN %s AND %s",
+			arg(origin.Latitude-band), arg(origin.Latitude+band),
+		))
+		where = append(where, fmt.Sprintf(
+			distanceCondition,
+			arg(origin.Latitude), arg(origin.Longitude), arg(float64(f.MaxDistance)), arg(model.LocationGrid),
+		))
+	}
+
+	if f.Cursor != "" {
+		c := &store.UserCursor{}
+		if err := store.DecodeCursor(f.Cursor, c); err != nil {
+			return nil, err
+		}
+
+		switch f.Sort {
+		case store.SortByAgeAsc:
+			where = append(where, fmt.Sprintf("(age, user_id) > (%s, %s)", arg(c.Age), arg(c.ID)))
+		case store.SortByAgeDesc:
+			age := arg(c.Age)
+			where = append(where, fmt.Sprintf("(age < %s OR (age = %s AND user_id > %s))", age, age, arg(c.ID)))
+		case store.SortByLastSeen:
+			var seen time.Time
+			if c.LastSeen != nil {
+				seen = *c.LastSeen
+			}
+			s := arg(seen)
+			where = append(where, fmt.Sprintf(
+				"(%[1]s < %[2]s OR (%[1]s = %[2]s AND user_id > %[3]s))",
+				lastSeenKey, s, arg(c.ID),
+			))
+		default:
+			where = append(where, "user_id > "+arg(c.ID))
+		}
+	}
+
+	orderBy := "user_id"
+	switch f.Sort {
+	case store.SortByAgeAsc:
+		orderBy = "age, user_id"
+	case store.SortByAgeDesc:
+		orderBy = "age DESC, user_id"
+	case store.SortByLastSeen:
+		orderBy = lastSeenKey + " DESC, user_id"
+	}
+
+	limit := f.PageLimit()
+	query := fmt.Sprintf(
+		"SELECT * FROM users WHERE %s ORDER BY %s LIMIT %s",
+		strings.Join(where, " AND "), orderBy, arg(limit+1),
+	)
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &store.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = store.NextUserCursor(f.Sort, users[limit-1])
+	}
+
+	return page, nil
+}
+
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	var usersCount int
+	err := r.s.db.QueryRow(
+		ctx,
+		"SELECT count(*) FROM users",
+	).Scan(&usersCount)
+	if err != nil {
+		return -1, err
+	}
+
+	return usersCount, nil
+}
+
+func (r *UserRepository) find(ctx context.Context, field string, value interface{}) ([]*model.User, error) {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		fmt.Sprintf("SELECT * FROM users WHERE %s = $1", field),
+		value,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	users, err := r.find(ctx, "user_id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return users[0], nil
+}
+
+// FindByIDs loads the users with the given ids in one query and returns them
+// in the order of ids, skipping the ones that do not exist.
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []int) ([]*model.User, error) {
+	if len(ids) == 0 {
+		return make([]*model.User, 0), nil
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.s.db.Query(
+		ctx,
+		"SELECT * FROM users WHERE user_id = ANY($1)",
+		ids,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*model.User, len(found))
+	for _, u := range found {
+		byID[u.ID] = u
+	}
+
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := byID[id]; ok {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*model.User, error) {
+	users, err := r.find(ctx, "login", login)
+	if err != nil {
+		return nil, err
+	}
+
+	return users[0], nil
+}
+
+// UpdateLocation sets the location of the user with id, or clears it if
+// l is nil.
+func (r *UserRepository) UpdateLocation(ctx context.Context, id int, l *model.Location) error {
+	var latitude, longitude *float64
+	if l != nil {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		latitude, longitude = &l.Latitude, &l.Longitude
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"UPDATE users SET latitude = $1, longitude = $2 WHERE user_id = $3",
+		latitude, longitude, id,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateLastSeen(ctx context.Context, id int, t time.Time) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		"UPDATE users SET last_seen_at = $1 WHERE user_id = $2",
+		t, id,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, u *model.User) error {
+	if err := u.ValidateUpdate(); err != nil {
+		return err
+	}
+
+	if u.Interests == nil {
+		u.Interests = make([]string, 0)
+	}
+
+	if u.Timezone == "" {
+		u.Timezone = defaultTimezone
+	}
+
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		`UPDATE users SET 
+			login = $1, 
+			name = $2, 
+			age = $3, 
+			gender = $4, 
+			city = $5, 
+			phone_number = $6, 
+			about = $7,
+			interests = $8,
+			timezone = $9
+		WHERE user_id = $10`,
+		u.Login, u.Name, u.Age, u.Gender, u.City, u.PhoneNumber, u.About, u.Interests, u.Timezone,
+		u.ID,
+	)
+
+	if err != nil {
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) delete(ctx context.Context, field string, value interface{}) error {
+	ctx, cancel := r.s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.s.db.Exec(
+		ctx,
+		fmt.Sprintf("DELETE FROM users WHERE %s = $1", field),
+		value,
+	)
+
+	if err != nil {
+		return translateDeleteError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) DeleteById(ctx context.Context, id int) error {
+	return r.delete(ctx, "user_id", id)
+}
@@ -0,0 +1,290 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	u := testUser(t)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	assert.NotNil(t, u.ID)
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u.ID)
+}
+
+func TestUserRepository_UniqueLoginConstraint(t *testing.T) {
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.PhoneNumber = "+79001234567"
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.Error(t, s.User().Create(context.Background(), u2))
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u1.ID)
+}
+
+func TestUserRepository_UniquePhoneNumberConstraint(t *testing.T) {
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.Error(t, s.User().Create(context.Background(), u2))
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", u1.ID)
+}
+
+func TestUserRepository_FindById(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	original_user := testUser(t)
+	err := original_user.EncryptPassword()
+	assert.NoError(t, err)
+
+	err = db.QueryRow(
+		context.Background(),
+		"INSERT INTO users(login, encrypted_password, name, age, gender, city, phone_number, about) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING user_id",
+		original_user.Login,
+		original_user.EncryptedPassword,
+		original_user.Name,
+		original_user.Age,
+		original_user.Gender,
+		original_user.City,
+		original_user.PhoneNumber,
+		original_user.About,
+	).Scan(&original_user.ID)
+
+	assert.NoError(t, err)
+
+	original_user.ClearPassword()
+
+	found_user, err := s.User().FindById(context.Background(), original_user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, original_user, found_user)
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", original_user.ID)
+}
+
+func TestUserRepository_FindByLogin(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	original_user := testUser(t)
+	err := original_user.EncryptPassword()
+	assert.NoError(t, err)
+
+	err = db.QueryRow(
+		context.Background(),
+		"INSERT INTO users(login, encrypted_password, name, age, gender, city, phone_number, about) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING user_id",
+		original_user.Login,
+		original_user.EncryptedPassword,
+		original_user.Name,
+		original_user.Age,
+		original_user.Gender,
+		original_user.City,
+		original_user.PhoneNumber,
+		original_user.About,
+	).Scan(&original_user.ID)
+
+	assert.NoError(t, err)
+
+	original_user.ClearPassword()
+
+	found_user, err := s.User().FindByLogin(context.Background(), original_user.Login)
+	assert.NoError(t, err)
+	assert.Equal(t, original_user, found_user)
+
+	db.Exec(context.Background(), "DELETE FROM users WHERE user_id = $1", original_user.ID)
+}
+
+func TestUserRepository_DeleteById(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u := testUser(t)
+	err := u.EncryptPassword()
+	assert.NoError(t, err)
+
+	err = db.QueryRow(
+		context.Background(),
+		"INSERT INTO users(login, encrypted_password, name, age, gender, city, phone_number, about) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING user_id",
+		u.Login,
+		u.EncryptedPassword,
+		u.Name,
+		u.Age,
+		u.Gender,
+		u.City,
+		u.PhoneNumber,
+		u.About,
+	).Scan(&u.ID)
+
+	assert.NoError(t, err)
+	assert.NoError(t, s.User().DeleteById(context.Background(), u.ID))
+	row := db.QueryRow(context.Background(), "SELECT * FROM users WHERE user_id = $1", u.ID)
+	assert.Equal(t, row.Scan(), pgx.ErrNoRows)
+}
+
+func TestUserRepository_DeleteByIdStillReferenced(t *testing.T) {
+	l := testLike(t)
+	defer deleteUsers(t, l.UserID, l.LikedUser)
+
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Like().Create(ctx, l))
+	assert.ErrorIs(t, s.User().DeleteById(ctx, l.LikedUser), store.ErrStillReferenced)
+
+	_, err := db.Exec(ctx, "DELETE FROM likes WHERE like_id = $1", l.ID)
+	assert.NoError(t, err)
+}
+
+func TestUserRepository_FindByFilters(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+	u2.PhoneNumber = "+79001234567"
+	u2.Age = 30
+	u2.City = "other_city"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.NoError(t, s.User().Create(context.Background(), u2))
+
+	page, err := s.User().FindByFilters(context.Background(), u1.ID, &store.UserFilter{
+		MinAge: 25,
+		Cities: []string{"other_city"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Equal(t, u2.ID, page.Users[0].ID)
+
+	page, err = s.User().FindByFilters(context.Background(), u1.ID, &store.UserFilter{MaxAge: 25})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	deleteUsers(t, u1.ID, u2.ID)
+}
+
+func TestUserRepository_FindByIDs(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+	u2.PhoneNumber = "+79001234567"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.NoError(t, s.User().Create(context.Background(), u2))
+
+	users, err := s.User().FindByIDs(context.Background(), []int{u2.ID, u1.ID})
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, u2.ID, users[0].ID)
+	assert.Equal(t, u1.ID, users[1].ID)
+
+	deleteUsers(t, u1.ID, u2.ID)
+}
+
+func TestUserRepository_Feed(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+	u2.PhoneNumber = "+79001234567"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.NoError(t, s.User().Create(context.Background(), u2))
+
+	page, err := s.User().Feed(context.Background(), u1.ID, &store.UserFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+
+	assert.NoError(t, s.Pass().Create(context.Background(), &model.Pass{UserID: u1.ID, PassedUser: u2.ID}))
+
+	page, err = s.User().Feed(context.Background(), u1.ID, &store.UserFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	page, err = s.User().Feed(context.Background(), u2.ID, &store.UserFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+
+	// An unmatch keeps both users out of each other's feed.
+	assert.NoError(t, s.Unmatch().Create(context.Background(), &model.Unmatch{UserID: u1.ID, UnmatchedUser: u2.ID}))
+
+	page, err = s.User().Feed(context.Background(), u2.ID, &store.UserFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	db.Exec(context.Background(), "DELETE FROM unmatches WHERE user_id = $1", u1.ID)
+	db.Exec(context.Background(), "DELETE FROM passes WHERE user_id = $1", u1.ID)
+	deleteUsers(t, u1.ID, u2.ID)
+}
+
+func TestUserRepository_UpdateLocation(t *testing.T) {
+	db := testDb(t)
+	defer db.Close()
+	s := sqlstore.New(db, 0)
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+	u2.PhoneNumber = "+79001234567"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.NoError(t, s.User().Create(context.Background(), u2))
+
+	origin := &model.Location{Latitude: 55.75, Longitude: 37.62}
+	assert.NoError(t, s.User().UpdateLocation(context.Background(), u2.ID, &model.Location{Latitude: 55.78, Longitude: 37.60}))
+
+	found, err := s.User().FindById(context.Background(), u2.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, found.Location)
+
+	page, err := s.User().FindByFilters(context.Background(), u1.ID, &store.UserFilter{MaxDistance: 5, Origin: origin})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+
+	page, err = s.User().FindByFilters(context.Background(), u1.ID, &store.UserFilter{MaxDistance: 1, Origin: origin})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	_, err = s.User().FindByFilters(context.Background(), u1.ID, &store.UserFilter{MaxDistance: 1})
+	assert.ErrorIs(t, err, store.ErrNoLocation)
+
+	deleteUsers(t, u1.ID, u2.ID)
+}
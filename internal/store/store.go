@@ -1,37 +1,26 @@
 package store
 
-import (
-	"github.com/jackc/pgx/v5"
-)
+import "context"
 
-type Store struct {
-	db              *pgx.Conn
-	userRepository  *UserRepository
-	likeRepository  *LikeRepository
-	matchRepository *MatchRepository
-}
-
-func NewStore(db *pgx.Conn) *Store {
-	return &Store{db: db}
-}
+// DefaultSuperLikesPerDay is the daily super like quota stores start with.
+const DefaultSuperLikesPerDay = 1
 
-func (s *Store) User() *UserRepository {
-	if s.userRepository == nil {
-		s.userRepository = &UserRepository{s}
-	}
-	return s.userRepository
-}
-
-func (s *Store) Like() *LikeRepository {
-	if s.likeRepository == nil {
-		s.likeRepository = &LikeRepository{s}
-	}
-	return s.likeRepository
-}
+type Store interface {
+	User() UserRepository
+	Like() LikeRepository
+	Match() MatchRepository
+	Pass() PassRepository
+	Preferences() PreferencesRepository
+	Unmatch() UnmatchRepository
+	Counter() CounterRepository
+	Block() BlockRepository
+	Report() ReportRepository
+	Message() MessageRepository
+	Event() EventRepository
+	Outbox() OutboxRepository
 
-func (s *Store) Match() *MatchRepository {
-	if s.matchRepository == nil {
-		s.matchRepository = &MatchRepository{s}
-	}
-	return s.matchRepository
+	// WithTx runs fn in a transaction. Repositories of tx see and change
+	// data only inside it; the transaction is committed if fn returns nil
+	// and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
 }
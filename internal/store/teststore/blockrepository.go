@@ -0,0 +1,67 @@
+package teststore
+
+import (
+	"context"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type BlockRepository struct {
+	s *Store
+}
+
+func (r *BlockRepository) Create(ctx context.Context, b *model.Block) error {
+	if b.UserID == b.BlockedUser {
+		return store.ErrSelfBlock
+	}
+
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[b.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[b.BlockedUser]; !ok {
+		return store.ErrNotFound
+	}
+
+	for _, v := range r.s.blocks {
+		if v.UserID == b.UserID && v.BlockedUser == b.BlockedUser {
+			return store.ErrAlreadyBlocked
+		}
+	}
+
+	r.s.lastBlockID++
+	b.ID = r.s.lastBlockID
+	b.CreatedAt = time.Now()
+	block := *b
+	r.s.blocks[b.ID] = &block
+
+	return nil
+}
+
+func (r *BlockRepository) Exists(ctx context.Context, user1, user2 int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.blocked(user1, user2), nil
+}
+
+func (r *BlockRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.blocks {
+		if v.UserID == userID || v.BlockedUser == userID {
+			delete(r.s.blocks, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockRepository_Create(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	b := &model.Block{UserID: l.UserID, BlockedUser: l.LikedUser}
+	assert.NoError(t, s.Block().Create(context.Background(), b))
+	assert.NotZero(t, b.ID)
+
+	assert.ErrorIs(t, s.Block().Create(context.Background(), &model.Block{UserID: l.UserID, BlockedUser: l.LikedUser}), store.ErrAlreadyBlocked)
+	assert.ErrorIs(t, s.Block().Create(context.Background(), &model.Block{UserID: l.UserID, BlockedUser: l.UserID}), store.ErrSelfBlock)
+
+	// Blocking back is allowed, a block hides the pair either way.
+	assert.NoError(t, s.Block().Create(context.Background(), &model.Block{UserID: l.LikedUser, BlockedUser: l.UserID}))
+
+	for _, pair := range [][2]int{{l.UserID, l.LikedUser}, {l.LikedUser, l.UserID}} {
+		blocked, err := s.Block().Exists(context.Background(), pair[0], pair[1])
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+	}
+
+	assert.NoError(t, s.Block().DeleteByUser(context.Background(), l.UserID))
+	blocked, err := s.Block().Exists(context.Background(), l.LikedUser, l.UserID)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+}
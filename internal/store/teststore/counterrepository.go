@@ -0,0 +1,134 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+// counterKey identifies a counter like the primary key of the sqlstore table.
+type counterKey struct {
+	userID      int
+	name        string
+	windowStart int64
+}
+
+func keyOf(c *model.Counter) counterKey {
+	return counterKey{c.UserID, c.Name, c.WindowStart.UnixNano()}
+}
+
+type CounterRepository struct {
+	s *Store
+}
+
+func (r *CounterRepository) Increment(ctx context.Context, c *model.Counter, max int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.increment(c, max)
+}
+
+// increment is Increment for the caller that holds r.s.mu.
+func (r *CounterRepository) increment(c *model.Counter, max int) error {
+	if max < 1 {
+		return store.ErrLimitExceeded
+	}
+
+	if _, ok := r.s.users[c.UserID]; !ok {
+		return store.ErrNotFound
+	}
+
+	for k, v := range r.s.counters {
+		if v.UserID == c.UserID && v.Name == c.Name && !v.WindowEnd.After(c.WindowStart) {
+			delete(r.s.counters, k)
+		}
+	}
+
+	stored, ok := r.s.counters[keyOf(c)]
+	if !ok {
+		stored = &model.Counter{}
+		*stored = *c
+		stored.Count = 0
+	}
+
+	if stored.Count >= max {
+		c.Count = stored.Count
+		return store.ErrLimitExceeded
+	}
+
+	updated := *stored
+	updated.Count++
+	r.s.counters[keyOf(c)] = &updated
+	c.Count = updated.Count
+
+	return nil
+}
+
+func (r *CounterRepository) FindLast(ctx context.Context, userID int, name string) (*model.Counter, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	c := r.findLast(userID, name)
+	if c == nil {
+		return nil, store.ErrNotFound
+	}
+
+	return c, nil
+}
+
+// findLast returns a copy of the counter of userID and name with the latest
+// window, or nil. The caller must hold r.s.mu.
+func (r *CounterRepository) findLast(userID int, name string) *model.Counter {
+	var last *model.Counter
+	for _, v := range r.s.counters {
+		if v.UserID == userID && v.Name == name && (last == nil || v.WindowEnd.After(last.WindowEnd)) {
+			last = v
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	c := *last
+	return &c
+}
+
+func (r *CounterRepository) FindByUser(ctx context.Context, userID int) ([]*model.Counter, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	counters := make([]*model.Counter, 0)
+	for _, v := range r.s.counters {
+		if v.UserID == userID && v.WindowEnd.After(now) {
+			c := *v
+			counters = append(counters, &c)
+		}
+	}
+
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Name != counters[j].Name {
+			return counters[i].Name < counters[j].Name
+		}
+		return counters[i].WindowStart.Before(counters[j].WindowStart)
+	})
+
+	return counters, nil
+}
+
+func (r *CounterRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for k, v := range r.s.counters {
+		if v.UserID == userID {
+			delete(r.s.counters, k)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterRepository_Increment(t *testing.T) {
+	s := teststore.New()
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	l := &model.Limit{Name: "burst", Max: 2, Period: time.Hour}
+	now := time.Now()
+
+	for i := 1; i <= 2; i++ {
+		c := l.Counter(u, nil, now)
+		assert.NoError(t, s.Counter().Increment(context.Background(), c, l.Max))
+		assert.Equal(t, i, c.Count)
+	}
+
+	c := l.Counter(u, nil, now)
+	assert.ErrorIs(t, s.Counter().Increment(context.Background(), c, l.Max), store.ErrLimitExceeded)
+	assert.Equal(t, 2, c.Count)
+
+	// The next window starts from zero and drops the expired one.
+	c = l.Counter(u, nil, now.Add(l.Period))
+	assert.NoError(t, s.Counter().Increment(context.Background(), c, l.Max))
+	assert.Equal(t, 1, c.Count)
+
+	counters, err := s.Counter().FindByUser(context.Background(), u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, counters, 1) {
+		assert.Equal(t, c.WindowStart, counters[0].WindowStart)
+	}
+}
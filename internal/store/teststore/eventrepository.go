@@ -0,0 +1,66 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type EventRepository struct {
+	s *Store
+}
+
+func (r *EventRepository) Create(ctx context.Context, e *model.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[e.UserID]; !ok {
+		return store.ErrNotFound
+	}
+
+	r.s.lastEventID++
+	e.ID = r.s.lastEventID
+	e.CreatedAt = time.Now()
+	event := *e
+	r.s.events[e.ID] = &event
+
+	return nil
+}
+
+func (r *EventRepository) FindAfter(ctx context.Context, userID, afterID, limit int) ([]*model.Event, error) {
+	r.s.mu.Lock()
+	events := make([]*model.Event, 0)
+	for _, v := range r.s.events {
+		if v.UserID == userID && v.ID > afterID {
+			e := *v
+			events = append(events, &e)
+		}
+	}
+	r.s.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+func (r *EventRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.events {
+		if v.UserID == userID {
+			delete(r.s.events, id)
+		}
+	}
+
+	return nil
+}
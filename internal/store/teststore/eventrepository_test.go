@@ -0,0 +1,40 @@
+package teststore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventRepository_FindAfter(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+	ctx := context.Background()
+
+	ids := make([]int, 0)
+	for _, userID := range []int{l.UserID, l.LikedUser, l.UserID, l.UserID} {
+		e := &model.Event{UserID: userID, Type: model.EventMatchCreated, Data: json.RawMessage(`{}`)}
+		assert.NoError(t, s.Event().Create(ctx, e))
+		if userID == l.UserID {
+			ids = append(ids, e.ID)
+		}
+	}
+
+	assert.ErrorIs(t, s.Event().Create(ctx, &model.Event{UserID: 100, Data: json.RawMessage(`{}`)}), store.ErrNotFound)
+
+	events, err := s.Event().FindAfter(ctx, l.UserID, ids[0], 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, ids[1], events[0].ID)
+		assert.Equal(t, ids[2], events[1].ID)
+	}
+
+	events, err = s.Event().FindAfter(ctx, l.UserID, 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
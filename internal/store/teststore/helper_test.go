@@ -0,0 +1,45 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+)
+
+func testUser(t *testing.T) *model.User {
+	t.Helper()
+
+	return &model.User{
+		Login:       "valid_login",
+		Password:    "valid_password",
+		Name:        "valid_name",
+		Age:         20,
+		Gender:      "male",
+		City:        "valid_city",
+		PhoneNumber: "+79999999999",
+		About:       "example text",
+	}
+}
+
+func testLike(t *testing.T, s *teststore.Store) *model.Like {
+	t.Helper()
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login += "1"
+	u2.PhoneNumber = "+79999999991"
+
+	if err := s.User().Create(context.Background(), u1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.User().Create(context.Background(), u2); err != nil {
+		t.Fatal(err)
+	}
+
+	return &model.Like{
+		UserID:    u1.ID,
+		LikedUser: u2.ID,
+	}
+}
@@ -0,0 +1,303 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type LikeRepository struct {
+	s *Store
+}
+
+func (r *LikeRepository) Create(ctx context.Context, l *model.Like) error {
+	if l.UserID == l.LikedUser {
+		return store.ErrSelfLike
+	}
+
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.create(l)
+}
+
+// create stores l. The caller must hold r.s.mu.
+func (r *LikeRepository) create(l *model.Like) error {
+	if _, ok := r.s.users[l.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[l.LikedUser]; !ok {
+		return store.ErrNotFound
+	}
+
+	for _, v := range r.s.likes {
+		if v.UserID == l.UserID && v.LikedUser == l.LikedUser {
+			return store.ErrAlreadyLiked
+		}
+	}
+
+	if l.Type == "" {
+		l.Type = model.LikeTypeRegular
+	}
+
+	if l.IsSuper() {
+		err := r.s.counterRepository.increment(r.superLikeCounter(l.UserID), r.s.superLikesPerDay)
+		if err == store.ErrLimitExceeded {
+			return store.ErrSuperLikeQuota
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	r.s.lastLikeID++
+	l.ID = r.s.lastLikeID
+	l.CreatedAt = time.Now()
+	like := *l
+	r.s.likes[l.ID] = &like
+
+	return nil
+}
+
+// superLikeCounter returns the super like counter of userID for the current
+// day, which starts at midnight in their timezone. The caller must hold r.s.mu.
+func (r *LikeRepository) superLikeCounter(userID int) *model.Counter {
+	u := &model.User{ID: userID}
+	if v, ok := r.s.users[userID]; ok {
+		u.Timezone = v.Timezone
+	}
+
+	prev := r.s.counterRepository.findLast(userID, model.SuperLikeCounter)
+
+	return model.SuperLikeLimit(r.s.superLikesPerDay).Counter(u, prev, time.Now())
+}
+
+func (r *LikeRepository) SuperLikeQuota(ctx context.Context, userID int) (*model.Quota, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[userID]; !ok {
+		return nil, store.ErrNotFound
+	}
+
+	c := r.superLikeCounter(userID)
+
+	return model.NewQuota(r.s.superLikesPerDay, c.Count, c.WindowEnd), nil
+}
+
+func (r *LikeRepository) Swipe(ctx context.Context, l *model.Like) (*model.Match, error) {
+	if l.UserID == l.LikedUser {
+		return nil, store.ErrSelfLike
+	}
+
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.create(l); err != nil {
+		return nil, err
+	}
+
+	reciprocal := false
+	for _, v := range r.s.likes {
+		if v.UserID == l.LikedUser && v.LikedUser == l.UserID {
+			reciprocal = true
+			break
+		}
+	}
+
+	if !reciprocal {
+		return nil, nil
+	}
+
+	m := &model.Match{
+		User1: l.UserID,
+		User2: l.LikedUser,
+	}
+
+	err := r.s.matchRepository.create(m)
+	if err == store.ErrAlreadyMatched {
+		return nil, nil
+	}
+	if err != nil {
+		// Swipe is atomic, so the like goes together with the match.
+		delete(r.s.likes, l.ID)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (r *LikeRepository) FindMatchLike(ctx context.Context, l *model.Like) (*model.Like, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, v := range r.s.likes {
+		if v.UserID == l.LikedUser && v.LikedUser == l.UserID {
+			like := *v
+			return &like, nil
+		}
+	}
+
+	return &model.Like{}, store.ErrNotFound
+}
+
+func (r *LikeRepository) FindLastByUser(ctx context.Context, userID int) (*model.Like, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var last *model.Like
+	for _, v := range r.s.likes {
+		if v.UserID != userID {
+			continue
+		}
+		if last == nil || v.CreatedAt.After(last.CreatedAt) || (v.CreatedAt.Equal(last.CreatedAt) && v.ID > last.ID) {
+			last = v
+		}
+	}
+
+	if last == nil {
+		return nil, store.ErrNotFound
+	}
+
+	like := *last
+	return &like, nil
+}
+
+func (r *LikeRepository) FindLikers(ctx context.Context, likedUser int, userIDs []int) ([]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ids := make([]int, 0)
+	for _, id := range userIDs {
+		for _, v := range r.s.likes {
+			if v.UserID == id && v.LikedUser == likedUser {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *LikeRepository) find(keep func(l *model.Like) bool) ([]*model.Like, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	likes := make([]*model.Like, 0)
+	for _, v := range r.s.likes {
+		if keep(v) {
+			like := *v
+			likes = append(likes, &like)
+		}
+	}
+
+	if len(likes) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	sort.Slice(likes, func(i, j int) bool {
+		return likes[i].ID < likes[j].ID
+	})
+
+	return likes, nil
+}
+
+func (r *LikeRepository) FindByUserID(ctx context.Context, userID int) ([]*model.Like, error) {
+	return r.find(func(l *model.Like) bool {
+		return l.UserID == userID
+	})
+}
+
+func (r *LikeRepository) FindByLikedUser(ctx context.Context, likedUser int) ([]*model.Like, error) {
+	return r.find(func(l *model.Like) bool {
+		return l.LikedUser == likedUser
+	})
+}
+
+func (r *LikeRepository) findPage(p *store.Pagination, keep func(l *model.Like) bool) (*store.LikePage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := r.find(func(l *model.Like) bool {
+		return l.ID > afterID && !r.s.blocked(l.UserID, l.LikedUser) && keep(l)
+	})
+	if err == store.ErrNotFound {
+		likes = make([]*model.Like, 0)
+	} else if err != nil {
+		return nil, err
+	}
+
+	limit := p.PageLimit()
+	page := &store.LikePage{Likes: likes}
+	if len(likes) > limit {
+		page.Likes = likes[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: likes[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *LikeRepository) FindPageByUserID(ctx context.Context, userID int, p *store.Pagination) (*store.LikePage, error) {
+	return r.findPage(p, func(l *model.Like) bool {
+		return l.UserID == userID
+	})
+}
+
+func (r *LikeRepository) FindPageByLikedUser(ctx context.Context, likedUser int, p *store.Pagination) (*store.LikePage, error) {
+	return r.findPage(p, func(l *model.Like) bool {
+		return l.LikedUser == likedUser
+	})
+}
+
+func (r *LikeRepository) delete(match func(l *model.Like) bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.likes {
+		if match(v) {
+			delete(r.s.likes, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *LikeRepository) DeleteByUsers(ctx context.Context, userId, likedUser int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.likes {
+		if v.UserID == userId && v.LikedUser == likedUser {
+			delete(r.s.likes, id)
+			return nil
+		}
+	}
+
+	return store.ErrNotFound
+}
+
+func (r *LikeRepository) DeleteByUser(ctx context.Context, userID int) error {
+	return r.delete(func(l *model.Like) bool {
+		return l.UserID == userID
+	})
+}
+
+func (r *LikeRepository) DeleteByLikedUser(ctx context.Context, likedUser int) error {
+	return r.delete(func(l *model.Like) bool {
+		return l.LikedUser == likedUser
+	})
+}
@@ -0,0 +1,199 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLikeRepository_Create(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+	assert.NotZero(t, l.ID)
+}
+
+func TestLikeRepository_DiffUsersConstraint(t *testing.T) {
+	s := teststore.New()
+	u := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	l := &model.Like{
+		UserID:    u.ID,
+		LikedUser: u.ID,
+	}
+
+	assert.Error(t, s.Like().Create(context.Background(), l))
+}
+
+func TestLikeRepository_MustBeUniquePairConstraint(t *testing.T) {
+	s := teststore.New()
+	l1 := testLike(t, s)
+	l2 := &model.Like{
+		UserID:    l1.UserID,
+		LikedUser: l1.LikedUser,
+	}
+
+	assert.NoError(t, s.Like().Create(context.Background(), l1))
+	assert.Error(t, s.Like().Create(context.Background(), l2))
+}
+
+func TestLikeRepository_FindMatchLike(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+
+	_, err := s.Like().FindMatchLike(context.Background(), l)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	back := &model.Like{
+		UserID:    l.LikedUser,
+		LikedUser: l.UserID,
+	}
+	assert.NoError(t, s.Like().Create(context.Background(), back))
+
+	found, err := s.Like().FindMatchLike(context.Background(), l)
+	assert.NoError(t, err)
+	assert.Equal(t, back, found)
+}
+
+func TestLikeRepository_FindPageByLikedUser(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	u := testUser(t)
+	u.Login = "third_login"
+	u.PhoneNumber = "+79999999992"
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+	assert.NoError(t, s.Like().Create(context.Background(), &model.Like{UserID: u.ID, LikedUser: l.LikedUser}))
+
+	p := &store.Pagination{Limit: 1}
+	page, err := s.Like().FindPageByLikedUser(context.Background(), l.LikedUser, p)
+	assert.NoError(t, err)
+	assert.Len(t, page.Likes, 1)
+	assert.Equal(t, l.UserID, page.Likes[0].UserID)
+	assert.NotEmpty(t, page.NextCursor)
+
+	p.Cursor = page.NextCursor
+	page, err = s.Like().FindPageByLikedUser(context.Background(), l.LikedUser, p)
+	assert.NoError(t, err)
+	assert.Len(t, page.Likes, 1)
+	assert.Equal(t, u.ID, page.Likes[0].UserID)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = s.Like().FindPageByLikedUser(context.Background(), l.UserID, &store.Pagination{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Likes)
+}
+
+func TestLikeRepository_Swipe(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	m, err := s.Like().Swipe(context.Background(), l)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = s.Like().Swipe(context.Background(), l)
+	assert.ErrorIs(t, err, store.ErrAlreadyLiked)
+
+	back := &model.Like{
+		UserID:    l.LikedUser,
+		LikedUser: l.UserID,
+	}
+	m, err = s.Like().Swipe(context.Background(), back)
+	assert.NoError(t, err)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, back.UserID, m.User1)
+		assert.Equal(t, back.LikedUser, m.User2)
+	}
+
+	// A match created directly with the pair reversed is the same match.
+	err = s.Match().Create(context.Background(), &model.Match{User1: l.UserID, User2: l.LikedUser})
+	assert.ErrorIs(t, err, store.ErrAlreadyMatched)
+
+	matches, err := s.Match().FindByUser(context.Background(), l.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
+
+func TestLikeRepository_SuperLikeQuota(t *testing.T) {
+	s := teststore.New()
+	s.SetSuperLikesPerDay(1)
+	l := testLike(t, s)
+	l.Type = model.LikeTypeSuper
+
+	u := testUser(t)
+	u.Login = "third_login"
+	u.PhoneNumber = "+79999999992"
+	u.Timezone = "Asia/Tokyo"
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	q, err := s.Like().SuperLikeQuota(context.Background(), l.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.Remaining)
+
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+
+	q, err = s.Like().SuperLikeQuota(context.Background(), l.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Remaining)
+	assert.True(t, q.ResetsAt.After(time.Now()))
+
+	_, err = s.Like().Swipe(context.Background(), &model.Like{UserID: l.UserID, LikedUser: u.ID, Type: model.LikeTypeSuper})
+	assert.ErrorIs(t, err, store.ErrSuperLikeQuota)
+	_, err = s.Like().Swipe(context.Background(), &model.Like{UserID: l.UserID, LikedUser: u.ID})
+	assert.NoError(t, err)
+
+	// Quotas are per user.
+	_, err = s.Like().Swipe(context.Background(), &model.Like{UserID: u.ID, LikedUser: l.UserID, Type: model.LikeTypeSuper})
+	assert.NoError(t, err)
+}
+
+func TestLikeRepository_SuperLikeWithdrawn(t *testing.T) {
+	s := teststore.New()
+	s.SetSuperLikesPerDay(1)
+	l := testLike(t, s)
+	l.Type = model.LikeTypeSuper
+	ctx := context.Background()
+
+	assert.NoError(t, s.Like().Create(ctx, l))
+	assert.NoError(t, s.Like().DeleteByUsers(ctx, l.UserID, l.LikedUser))
+
+	// Withdrawing the super like does not give the quota back.
+	q, err := s.Like().SuperLikeQuota(ctx, l.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Remaining)
+	assert.ErrorIs(t, s.Like().Create(ctx, &model.Like{UserID: l.UserID, LikedUser: l.LikedUser, Type: model.LikeTypeSuper}), store.ErrSuperLikeQuota)
+}
+
+func TestLikeRepository_SuperLikeTimezoneChange(t *testing.T) {
+	s := teststore.New()
+	s.SetSuperLikesPerDay(1)
+	l := testLike(t, s)
+	l.Type = model.LikeTypeSuper
+	ctx := context.Background()
+
+	assert.NoError(t, s.Like().Create(ctx, l))
+
+	assert.NoError(t, s.Like().DeleteByUsers(ctx, l.UserID, l.LikedUser))
+
+	u, err := s.User().FindById(ctx, l.UserID)
+	assert.NoError(t, err)
+	u.Timezone = "Pacific/Kiritimati"
+	assert.NoError(t, s.User().Update(ctx, u))
+
+	// The day that has begun goes on in the new timezone.
+	q, err := s.Like().SuperLikeQuota(ctx, l.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Remaining)
+	assert.ErrorIs(t, s.Like().Create(ctx, &model.Like{UserID: l.UserID, LikedUser: l.LikedUser, Type: model.LikeTypeSuper}), store.ErrSuperLikeQuota)
+}
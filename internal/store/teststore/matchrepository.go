@@ -0,0 +1,160 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type MatchRepository struct {
+	s *Store
+}
+
+func (r *MatchRepository) Create(ctx context.Context, m *model.Match) error {
+	if m.User1 == m.User2 {
+		return store.ErrSelfMatch
+	}
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[m.User1]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[m.User2]; !ok {
+		return store.ErrNotFound
+	}
+
+	return r.create(m)
+}
+
+// create stores m with its pair ordered like the sqlstore does.
+// The caller must hold r.s.mu.
+func (r *MatchRepository) create(m *model.Match) error {
+	user1, user2 := m.Pair()
+	for _, v := range r.s.matches {
+		if v.User1 == user1 && v.User2 == user2 {
+			return store.ErrAlreadyMatched
+		}
+	}
+
+	r.s.lastMatchID++
+	m.ID = r.s.lastMatchID
+	m.CreatedAt = time.Now()
+	r.s.matches[m.ID] = &model.Match{
+		ID:        m.ID,
+		User1:     user1,
+		User2:     user2,
+		CreatedAt: m.CreatedAt,
+	}
+
+	return nil
+}
+
+func (r *MatchRepository) FindByUser(ctx context.Context, userID int) ([]*model.Match, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	matches := make([]*model.Match, 0)
+	for _, v := range r.s.matches {
+		if v.User1 != userID && v.User2 != userID {
+			continue
+		}
+
+		m := *v
+		if m.User1 != userID {
+			m.User1, m.User2 = m.User2, m.User1
+		}
+
+		matches = append(matches, &m)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].ID < matches[j].ID
+	})
+
+	return matches, nil
+}
+
+func (r *MatchRepository) FindByUsers(ctx context.Context, user1, user2 int) (*model.Match, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	first, second := (&model.Match{User1: user1, User2: user2}).Pair()
+	for _, v := range r.s.matches {
+		if v.User1 == first && v.User2 == second {
+			m := *v
+			if m.User1 != user1 {
+				m.User1, m.User2 = m.User2, m.User1
+			}
+			return &m, nil
+		}
+	}
+
+	return nil, store.ErrNotFound
+}
+
+func (r *MatchRepository) FindPageByUser(ctx context.Context, userID int, p *store.Pagination) (*store.MatchPage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := r.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	matches := make([]*model.Match, 0)
+	for _, m := range all {
+		if m.ID > afterID && !r.s.blocked(m.User1, m.User2) {
+			matches = append(matches, m)
+		}
+	}
+	r.s.mu.Unlock()
+
+	limit := p.PageLimit()
+	page := &store.MatchPage{Matches: matches}
+	if len(matches) > limit {
+		page.Matches = matches[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: matches[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *MatchRepository) DeleteByUsers(ctx context.Context, user1, user2 int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user1, user2 = (&model.Match{User1: user1, User2: user2}).Pair()
+	for matchID, v := range r.s.matches {
+		if v.User1 == user1 && v.User2 == user2 {
+			delete(r.s.matches, matchID)
+			return nil
+		}
+	}
+
+	return store.ErrNotFound
+}
+
+func (r *MatchRepository) DeleteByUser(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for matchID, v := range r.s.matches {
+		if v.User1 == id || v.User2 == id {
+			delete(r.s.matches, matchID)
+		}
+	}
+
+	return nil
+}
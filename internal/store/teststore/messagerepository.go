@@ -0,0 +1,150 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type MessageRepository struct {
+	s *Store
+}
+
+func (r *MessageRepository) Create(ctx context.Context, m *model.Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	match, ok := r.s.matches[m.MatchID]
+	if !ok || (match.User1 != m.Sender && match.User2 != m.Sender) {
+		return store.ErrThreadLocked
+	}
+
+	r.s.lastMessageID++
+	m.ID = r.s.lastMessageID
+	m.Recipient = match.User1
+	if m.Recipient == m.Sender {
+		m.Recipient = match.User2
+	}
+	m.CreatedAt = time.Now()
+	m.Edited, m.Deleted = false, false
+
+	message := *m
+	r.s.messages[m.ID] = &message
+
+	return nil
+}
+
+func (r *MessageRepository) FindThread(ctx context.Context, matchID int) (*model.Thread, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if m, ok := r.s.matches[matchID]; ok {
+		return &model.Thread{MatchID: m.ID, User1: m.User1, User2: m.User2}, nil
+	}
+
+	for _, v := range r.s.messages {
+		if v.MatchID != matchID {
+			continue
+		}
+
+		t := &model.Thread{MatchID: matchID, User1: v.Sender, User2: v.Recipient, Locked: true}
+		if t.User1 > t.User2 {
+			t.User1, t.User2 = t.User2, t.User1
+		}
+		return t, nil
+	}
+
+	return nil, store.ErrNotFound
+}
+
+func (r *MessageRepository) FindPageByMatch(ctx context.Context, matchID int, p *store.Pagination) (*store.MessagePage, error) {
+	beforeID, err := p.BeforeID()
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	messages := make([]*model.Message, 0)
+	for _, v := range r.s.messages {
+		if v.MatchID == matchID && (beforeID == 0 || v.ID < beforeID) {
+			m := *v
+			messages = append(messages, &m)
+		}
+	}
+	r.s.mu.Unlock()
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID > messages[j].ID
+	})
+
+	limit := p.PageLimit()
+	page := &store.MessagePage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: messages[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+// change applies fn to a copy of the live message of m.Sender with m.ID in
+// m.MatchID, stores the copy and fills m in from it. It fails with
+// ErrThreadLocked like Create.
+func (r *MessageRepository) change(m *model.Message, fn func(v *model.Message)) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	match, ok := r.s.matches[m.MatchID]
+	if !ok || (match.User1 != m.Sender && match.User2 != m.Sender) {
+		return store.ErrThreadLocked
+	}
+
+	v, ok := r.s.messages[m.ID]
+	if !ok || v.MatchID != m.MatchID || v.Sender != m.Sender || v.Deleted {
+		return store.ErrNotFound
+	}
+
+	message := *v
+	fn(&message)
+	r.s.messages[m.ID] = &message
+	*m = message
+
+	return nil
+}
+
+func (r *MessageRepository) Update(ctx context.Context, m *model.Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	body := m.Body
+	return r.change(m, func(v *model.Message) {
+		v.Body, v.Edited = body, true
+	})
+}
+
+func (r *MessageRepository) Delete(ctx context.Context, m *model.Message) error {
+	return r.change(m, func(v *model.Message) {
+		v.Body, v.Deleted = "", true
+	})
+}
+
+func (r *MessageRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.messages {
+		if v.Sender == userID || v.Recipient == userID {
+			delete(r.s.messages, id)
+		}
+	}
+
+	return nil
+}
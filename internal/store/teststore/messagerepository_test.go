@@ -0,0 +1,75 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRepository_FindPageByMatch(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+	ctx := context.Background()
+
+	match := &model.Match{User1: l.UserID, User2: l.LikedUser}
+	assert.NoError(t, s.Match().Create(ctx, match))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Message().Create(ctx, &model.Message{MatchID: match.ID, Sender: l.UserID, Body: "hi"}))
+	}
+
+	page, err := s.Message().FindPageByMatch(ctx, match.ID, &store.Pagination{Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Messages, 2) {
+		assert.Greater(t, page.Messages[0].ID, page.Messages[1].ID)
+		assert.Equal(t, l.LikedUser, page.Messages[0].Recipient)
+	}
+	assert.NotEmpty(t, page.NextCursor)
+
+	next, err := s.Message().FindPageByMatch(ctx, match.ID, &store.Pagination{Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	if assert.Len(t, next.Messages, 1) {
+		assert.Less(t, next.Messages[0].ID, page.Messages[1].ID)
+	}
+	assert.Empty(t, next.NextCursor)
+}
+
+func TestMessageRepository_Delete(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+	ctx := context.Background()
+
+	match := &model.Match{User1: l.UserID, User2: l.LikedUser}
+	assert.NoError(t, s.Match().Create(ctx, match))
+
+	m := &model.Message{MatchID: match.ID, Sender: l.UserID, Body: "hi"}
+	assert.NoError(t, s.Message().Create(ctx, m))
+
+	assert.ErrorIs(t, s.Message().Delete(ctx, &model.Message{ID: m.ID, MatchID: match.ID, Sender: l.LikedUser}), store.ErrNotFound)
+
+	assert.NoError(t, s.Message().Delete(ctx, m))
+	assert.True(t, m.Deleted)
+	assert.Empty(t, m.Body)
+
+	m.Body = "edited"
+	assert.ErrorIs(t, s.Message().Update(ctx, m), store.ErrNotFound)
+	assert.ErrorIs(t, s.Message().Delete(ctx, m), store.ErrNotFound)
+
+	live := &model.Message{MatchID: match.ID, Sender: l.UserID, Body: "hi"}
+	assert.NoError(t, s.Message().Create(ctx, live))
+
+	// Unmatching locks the thread but keeps it.
+	assert.NoError(t, s.Match().DeleteByUsers(ctx, l.UserID, l.LikedUser))
+	th, err := s.Message().FindThread(ctx, match.ID)
+	assert.NoError(t, err)
+	assert.True(t, th.Locked)
+	assert.ErrorIs(t, s.Message().Create(ctx, &model.Message{MatchID: match.ID, Sender: l.UserID, Body: "hi"}), store.ErrThreadLocked)
+
+	live.Body = "edited"
+	assert.ErrorIs(t, s.Message().Update(ctx, live), store.ErrThreadLocked)
+	assert.ErrorIs(t, s.Message().Delete(ctx, live), store.ErrThreadLocked)
+}
@@ -0,0 +1,130 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type OutboxRepository struct {
+	s *Store
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, m *model.OutboxMessage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.lastOutboxID++
+	m.ID = r.s.lastOutboxID
+	m.Status = model.OutboxPending
+	m.Attempts = 0
+	m.LastError = ""
+	m.CreatedAt = time.Now()
+	m.NextAttemptAt = m.CreatedAt
+	message := *m
+	r.s.outbox[m.ID] = &message
+
+	return nil
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	messages := r.find(func(m *model.OutboxMessage) bool {
+		return m.Status == model.OutboxPending && !m.NextAttemptAt.After(now)
+	}, limit)
+
+	for _, m := range messages {
+		m.NextAttemptAt = now.Add(lease)
+		message := *m
+		r.s.outbox[m.ID] = &message
+	}
+
+	return messages, nil
+}
+
+func (r *OutboxRepository) FindByStatus(ctx context.Context, status string, afterID, limit int) ([]*model.OutboxMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.find(func(m *model.OutboxMessage) bool {
+		return m.Status == status && m.ID > afterID
+	}, limit), nil
+}
+
+func (r *OutboxRepository) Update(ctx context.Context, m *model.OutboxMessage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v, ok := r.s.outbox[m.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	message := *v
+	message.Status = m.Status
+	message.Attempts = m.Attempts
+	message.NextAttemptAt = m.NextAttemptAt
+	message.LastError = m.LastError
+	r.s.outbox[m.ID] = &message
+
+	return nil
+}
+
+func (r *OutboxRepository) Replay(ctx context.Context, id int, now time.Time) (*model.OutboxMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	v, ok := r.s.outbox[id]
+	if !ok || v.Status != model.OutboxDead {
+		return nil, store.ErrNotFound
+	}
+
+	message := *v
+	message.Status = model.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = now
+	r.s.outbox[id] = &message
+
+	m := message
+	return &m, nil
+}
+
+func (r *OutboxRepository) DeleteByID(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.outbox[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(r.s.outbox, id)
+
+	return nil
+}
+
+// find returns copies of up to limit messages that match, oldest first.
+// The caller must hold r.s.mu.
+func (r *OutboxRepository) find(match func(m *model.OutboxMessage) bool, limit int) []*model.OutboxMessage {
+	messages := make([]*model.OutboxMessage, 0)
+	for _, v := range r.s.outbox {
+		if match(v) {
+			m := *v
+			messages = append(messages, &m)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+
+	return messages
+}
@@ -0,0 +1,76 @@
+package teststore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRepository_Claim(t *testing.T) {
+	s := teststore.New()
+	ctx := context.Background()
+
+	messages := make([]*model.OutboxMessage, 3)
+	for i := range messages {
+		messages[i] = &model.OutboxMessage{Type: model.DomainUserDeleted, Payload: json.RawMessage(`{"user_id":1}`)}
+		assert.NoError(t, s.Outbox().Create(ctx, messages[i]))
+		assert.Equal(t, model.OutboxPending, messages[i].Status)
+	}
+
+	now := time.Now()
+	claimed, err := s.Outbox().Claim(ctx, now, time.Minute, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 2) {
+		assert.Equal(t, messages[0].ID, claimed[0].ID)
+		assert.Equal(t, messages[1].ID, claimed[1].ID)
+	}
+
+	claimed, err = s.Outbox().Claim(ctx, now, time.Minute, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, messages[2].ID, claimed[0].ID)
+	}
+
+	claimed, err = s.Outbox().Claim(ctx, now.Add(time.Minute), time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 3)
+
+	dead := claimed[0]
+	dead.Status = model.OutboxDead
+	dead.Attempts = 1
+	dead.LastError = "boom"
+	assert.NoError(t, s.Outbox().Update(ctx, dead))
+
+	claimed, err = s.Outbox().Claim(ctx, now.Add(time.Hour), time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 2)
+
+	found, err := s.Outbox().FindByStatus(ctx, model.OutboxDead, 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "boom", found[0].LastError)
+		assert.Equal(t, 1, found[0].Attempts)
+	}
+
+	// A replayed message is pending again with all of its attempts.
+	replayed, err := s.Outbox().Replay(ctx, dead.ID, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, model.OutboxPending, replayed.Status)
+	assert.Zero(t, replayed.Attempts)
+	_, err = s.Outbox().Replay(ctx, dead.ID, now)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	claimed, err = s.Outbox().Claim(ctx, now.Add(2*time.Hour), time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 3)
+
+	assert.NoError(t, s.Outbox().DeleteByID(ctx, dead.ID))
+	assert.ErrorIs(t, s.Outbox().DeleteByID(ctx, dead.ID), store.ErrNotFound)
+	assert.ErrorIs(t, s.Outbox().Update(ctx, dead), store.ErrNotFound)
+}
@@ -0,0 +1,95 @@
+package teststore
+
+import (
+	"context"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type PassRepository struct {
+	s *Store
+}
+
+func (r *PassRepository) Create(ctx context.Context, p *model.Pass) error {
+	if p.UserID == p.PassedUser {
+		return store.ErrSelfPass
+	}
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[p.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[p.PassedUser]; !ok {
+		return store.ErrNotFound
+	}
+
+	for _, v := range r.s.passes {
+		if v.UserID == p.UserID && v.PassedUser == p.PassedUser {
+			return store.ErrAlreadyPassed
+		}
+	}
+
+	r.s.lastPassID++
+	p.ID = r.s.lastPassID
+	p.CreatedAt = time.Now()
+	pass := *p
+	r.s.passes[p.ID] = &pass
+
+	return nil
+}
+
+func (r *PassRepository) FindLastByUser(ctx context.Context, userID int) (*model.Pass, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var last *model.Pass
+	for _, v := range r.s.passes {
+		if v.UserID != userID {
+			continue
+		}
+		if last == nil || v.CreatedAt.After(last.CreatedAt) || (v.CreatedAt.Equal(last.CreatedAt) && v.ID > last.ID) {
+			last = v
+		}
+	}
+
+	if last == nil {
+		return nil, store.ErrNotFound
+	}
+
+	pass := *last
+	return &pass, nil
+}
+
+func (r *PassRepository) DeleteByID(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.passes[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(r.s.passes, id)
+
+	return nil
+}
+
+func (r *PassRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.passes {
+		if v.UserID == userID || v.PassedUser == userID {
+			delete(r.s.passes, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package teststore
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type PhotoStore struct {
+	mu     sync.Mutex
+	photos map[string][]byte
+}
+
+func NewPhotoStore() *PhotoStore {
+	return &PhotoStore{photos: make(map[string][]byte)}
+}
+
+func (ps *PhotoStore) Create(photo []byte, id int) error {
+	if http.DetectContentType(photo) != "image/jpeg" {
+		return store.ErrInvalidPhoto
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p := make([]byte, len(photo))
+	copy(p, photo)
+	ps.photos[strconv.Itoa(id)] = p
+
+	return nil
+}
+
+func (ps *PhotoStore) FindById(id int) ([]byte, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.photos[strconv.Itoa(id)]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return p, nil
+}
+
+func (ps *PhotoStore) DeleteByName(fileName string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.photos[fileName]; !ok {
+		return store.ErrNotFound
+	}
+	delete(ps.photos, fileName)
+
+	return nil
+}
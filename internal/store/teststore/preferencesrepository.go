@@ -0,0 +1,57 @@
+package teststore
+
+import (
+	"context"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type PreferencesRepository struct {
+	s *Store
+}
+
+func copyPreferences(p *model.Preferences) *model.Preferences {
+	c := *p
+	c.Genders = append(make([]string, 0, len(p.Genders)), p.Genders...)
+	c.Cities = append(make([]string, 0, len(p.Cities)), p.Cities...)
+	return &c
+}
+
+func (r *PreferencesRepository) FindByUser(ctx context.Context, userID int) (*model.Preferences, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.preferences[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return copyPreferences(p), nil
+}
+
+func (r *PreferencesRepository) Save(ctx context.Context, p *model.Preferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[p.UserID]; !ok {
+		return store.ErrNotFound
+	}
+
+	r.s.preferences[p.UserID] = copyPreferences(p)
+
+	return nil
+}
+
+func (r *PreferencesRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.preferences, userID)
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type ReportRepository struct {
+	s *Store
+}
+
+func (r *ReportRepository) Create(ctx context.Context, rep *model.Report) error {
+	if rep.UserID == rep.ReportedUser {
+		return store.ErrSelfReport
+	}
+
+	if err := rep.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[rep.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[rep.ReportedUser]; !ok {
+		return store.ErrNotFound
+	}
+
+	r.s.lastReportID++
+	rep.ID = r.s.lastReportID
+	rep.Status = model.ReportStatusPending
+	rep.CreatedAt = time.Now()
+	report := *rep
+	r.s.reports[rep.ID] = &report
+
+	return nil
+}
+
+func (r *ReportRepository) FindByReportedUser(ctx context.Context, reportedUser int) ([]*model.Report, error) {
+	r.s.mu.Lock()
+	reports := make([]*model.Report, 0)
+	for _, v := range r.s.reports {
+		if v.ReportedUser == reportedUser {
+			rep := *v
+			reports = append(reports, &rep)
+		}
+	}
+	r.s.mu.Unlock()
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ID < reports[j].ID
+	})
+
+	return reports, nil
+}
+
+func (r *ReportRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.reports {
+		if v.UserID == userID {
+			delete(r.s.reports, id)
+		}
+	}
+
+	return nil
+}
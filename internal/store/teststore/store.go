@@ -0,0 +1,219 @@
+package teststore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type Store struct {
+	mu                    sync.Mutex
+	users                 map[int]*model.User
+	likes                 map[int]*model.Like
+	matches               map[int]*model.Match
+	passes                map[int]*model.Pass
+	preferences           map[int]*model.Preferences
+	unmatches             map[int]*model.Unmatch
+	counters              map[counterKey]*model.Counter
+	blocks                map[int]*model.Block
+	reports               map[int]*model.Report
+	messages              map[int]*model.Message
+	events                map[int]*model.Event
+	outbox                map[int]*model.OutboxMessage
+	lastUserID            int
+	lastLikeID            int
+	lastMatchID           int
+	lastPassID            int
+	lastUnmatchID         int
+	lastBlockID           int
+	lastReportID          int
+	lastMessageID         int
+	lastEventID           int
+	lastOutboxID          int
+	superLikesPerDay      int
+	userRepository        *UserRepository
+	likeRepository        *LikeRepository
+	matchRepository       *MatchRepository
+	passRepository        *PassRepository
+	preferencesRepository *PreferencesRepository
+	unmatchRepository     *UnmatchRepository
+	counterRepository     *CounterRepository
+	blockRepository       *BlockRepository
+	reportRepository      *ReportRepository
+	messageRepository     *MessageRepository
+	eventRepository       *EventRepository
+	outboxRepository      *OutboxRepository
+}
+
+func New() *Store {
+	s := &Store{
+		users:       make(map[int]*model.User),
+		likes:       make(map[int]*model.Like),
+		matches:     make(map[int]*model.Match),
+		passes:      make(map[int]*model.Pass),
+		preferences: make(map[int]*model.Preferences),
+		unmatches:   make(map[int]*model.Unmatch),
+		counters:    make(map[counterKey]*model.Counter),
+		blocks:      make(map[int]*model.Block),
+		reports:     make(map[int]*model.Report),
+		messages:    make(map[int]*model.Message),
+		events:      make(map[int]*model.Event),
+		outbox:      make(map[int]*model.OutboxMessage),
+
+		superLikesPerDay: store.DefaultSuperLikesPerDay,
+	}
+
+	s.userRepository = &UserRepository{s}
+	s.likeRepository = &LikeRepository{s}
+	s.matchRepository = &MatchRepository{s}
+	s.passRepository = &PassRepository{s}
+	s.preferencesRepository = &PreferencesRepository{s}
+	s.unmatchRepository = &UnmatchRepository{s}
+	s.counterRepository = &CounterRepository{s}
+	s.blockRepository = &BlockRepository{s}
+	s.reportRepository = &ReportRepository{s}
+	s.messageRepository = &MessageRepository{s}
+	s.eventRepository = &EventRepository{s}
+	s.outboxRepository = &OutboxRepository{s}
+
+	return s
+}
+
+// SetSuperLikesPerDay changes the daily super like quota of every user.
+func (s *Store) SetSuperLikesPerDay(n int) {
+	s.superLikesPerDay = n
+}
+
+func (s *Store) User() store.UserRepository {
+	return s.userRepository
+}
+
+func (s *Store) Like() store.LikeRepository {
+	return s.likeRepository
+}
+
+func (s *Store) Match() store.MatchRepository {
+	return s.matchRepository
+}
+
+func (s *Store) Pass() store.PassRepository {
+	return s.passRepository
+}
+
+func (s *Store) Preferences() store.PreferencesRepository {
+	return s.preferencesRepository
+}
+
+func (s *Store) Unmatch() store.UnmatchRepository {
+	return s.unmatchRepository
+}
+
+func (s *Store) Counter() store.CounterRepository {
+	return s.counterRepository
+}
+
+func (s *Store) Block() store.BlockRepository {
+	return s.blockRepository
+}
+
+func (s *Store) Report() store.ReportRepository {
+	return s.reportRepository
+}
+
+func (s *Store) Message() store.MessageRepository {
+	return s.messageRepository
+}
+
+func (s *Store) Event() store.EventRepository {
+	return s.eventRepository
+}
+
+func (s *Store) Outbox() store.OutboxRepository {
+	return s.outboxRepository
+}
+
+// blocked reports whether either of the users has blocked the other.
+// The caller must hold s.mu.
+func (s *Store) blocked(user1, user2 int) bool {
+	for _, b := range s.blocks {
+		if (b.UserID == user1 && b.BlockedUser == user2) || (b.UserID == user2 && b.BlockedUser == user1) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTx runs fn against a snapshot of the store and publishes the snapshot
+// only if fn succeeds. The store stays locked until then, so reads and writes
+// made outside of the transaction wait for it instead of being lost when the
+// snapshot is published. fn must therefore use only tx.
+func (s *Store) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.users, s.likes, s.matches = tx.users, tx.likes, tx.matches
+	s.passes, s.preferences, s.unmatches = tx.passes, tx.preferences, tx.unmatches
+	s.counters, s.blocks, s.reports = tx.counters, tx.blocks, tx.reports
+	s.messages, s.events, s.outbox = tx.messages, tx.events, tx.outbox
+	s.lastUserID, s.lastLikeID, s.lastMatchID = tx.lastUserID, tx.lastLikeID, tx.lastMatchID
+	s.lastPassID, s.lastUnmatchID = tx.lastPassID, tx.lastUnmatchID
+	s.lastBlockID, s.lastReportID, s.lastMessageID = tx.lastBlockID, tx.lastReportID, tx.lastMessageID
+	s.lastEventID, s.lastOutboxID = tx.lastEventID, tx.lastOutboxID
+
+	return nil
+}
+
+func (s *Store) clone() *Store {
+	c := New()
+
+	for k, v := range s.users {
+		c.users[k] = v
+	}
+	for k, v := range s.likes {
+		c.likes[k] = v
+	}
+	for k, v := range s.matches {
+		c.matches[k] = v
+	}
+	for k, v := range s.passes {
+		c.passes[k] = v
+	}
+	for k, v := range s.preferences {
+		c.preferences[k] = v
+	}
+	for k, v := range s.unmatches {
+		c.unmatches[k] = v
+	}
+	for k, v := range s.counters {
+		c.counters[k] = v
+	}
+	for k, v := range s.blocks {
+		c.blocks[k] = v
+	}
+	for k, v := range s.reports {
+		c.reports[k] = v
+	}
+	for k, v := range s.messages {
+		c.messages[k] = v
+	}
+	for k, v := range s.events {
+		c.events[k] = v
+	}
+	for k, v := range s.outbox {
+		c.outbox[k] = v
+	}
+	c.lastUserID, c.lastLikeID, c.lastMatchID = s.lastUserID, s.lastLikeID, s.lastMatchID
+	c.lastPassID, c.lastUnmatchID = s.lastPassID, s.lastUnmatchID
+	c.lastBlockID, c.lastReportID, c.lastMessageID = s.lastBlockID, s.lastReportID, s.lastMessageID
+	c.lastEventID, c.lastOutboxID = s.lastEventID, s.lastOutboxID
+	c.superLikesPerDay = s.superLikesPerDay
+
+	return c
+}
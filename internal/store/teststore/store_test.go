@@ -0,0 +1,81 @@
+package teststore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_WithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	testCases := []struct {
+		name          string
+		fnErr         error
+		expectedCount int
+	}{
+		{
+			name:          "commit",
+			fnErr:         nil,
+			expectedCount: 1,
+		},
+		{
+			name:          "rollback",
+			fnErr:         errFailed,
+			expectedCount: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := teststore.New()
+
+			err := s.WithTx(context.Background(), func(tx store.Store) error {
+				if err := tx.User().Create(context.Background(), testUser(t)); err != nil {
+					return err
+				}
+				return tc.fnErr
+			})
+			assert.Equal(t, tc.fnErr, err)
+
+			count, err := s.User().Count(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
+
+func TestStore_WithTxConcurrentWrite(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		<-started
+		// Waits for the transaction and must not be overwritten by it.
+		done <- s.Like().Create(context.Background(), l)
+	}()
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		close(started)
+		u := testUser(t)
+		u.Login = "tx_login"
+		u.PhoneNumber = "+79999999992"
+		return tx.User().Create(context.Background(), u)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, <-done)
+
+	count, err := s.User().Count(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	likes, err := s.Like().FindByUserID(context.Background(), l.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, likes, 1)
+}
@@ -0,0 +1,70 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type UnmatchRepository struct {
+	s *Store
+}
+
+func (r *UnmatchRepository) Create(ctx context.Context, u *model.Unmatch) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[u.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := r.s.users[u.UnmatchedUser]; !ok {
+		return store.ErrNotFound
+	}
+
+	r.s.lastUnmatchID++
+	u.ID = r.s.lastUnmatchID
+	u.CreatedAt = time.Now()
+	unmatch := *u
+	r.s.unmatches[u.ID] = &unmatch
+
+	return nil
+}
+
+func (r *UnmatchRepository) FindByUser(ctx context.Context, userID int) ([]*model.Unmatch, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	unmatches := make([]*model.Unmatch, 0)
+	for _, v := range r.s.unmatches {
+		if v.UserID == userID || v.UnmatchedUser == userID {
+			u := *v
+			unmatches = append(unmatches, &u)
+		}
+	}
+
+	sort.Slice(unmatches, func(i, j int) bool {
+		return unmatches[i].ID < unmatches[j].ID
+	})
+
+	return unmatches, nil
+}
+
+func (r *UnmatchRepository) DeleteByUser(ctx context.Context, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, v := range r.s.unmatches {
+		if v.UserID == userID || v.UnmatchedUser == userID {
+			delete(r.s.unmatches, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,451 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+func copyUser(u *model.User) *model.User {
+	c := *u
+	c.Password = ""
+	if u.Location != nil {
+		l := *u.Location
+		c.Location = &l
+	}
+	if u.Interests != nil {
+		c.Interests = append(make([]string, 0, len(u.Interests)), u.Interests...)
+	}
+	return &c
+}
+
+func (r *UserRepository) checkUnique(u *model.User) error {
+	for _, v := range r.s.users {
+		if v.ID == u.ID {
+			continue
+		}
+		if v.Login == u.Login {
+			return store.ErrDuplicateLogin
+		}
+		if v.PhoneNumber == u.PhoneNumber {
+			return store.ErrDuplicatePhone
+		}
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	if err := u.EncryptPassword(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkUnique(u); err != nil {
+		return err
+	}
+
+	if u.Timezone == "" {
+		u.Timezone = "UTC"
+	}
+
+	r.s.lastUserID++
+	u.ID = r.s.lastUserID
+	r.s.users[u.ID] = copyUser(u)
+
+	return nil
+}
+
+// sorted returns copies of the stored users ordered by id, skipping the ones
+// rejected by keep.
+func (r *UserRepository) sorted(keep func(u *model.User) bool) []*model.User {
+	users := make([]*model.User, 0)
+	for _, u := range r.s.users {
+		if keep(u) {
+			users = append(users, copyUser(u))
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return users
+}
+
+func (r *UserRepository) All(ctx context.Context, currentUserID int) ([]*model.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.sorted(func(u *model.User) bool {
+		return u.ID != currentUserID && !r.s.blocked(currentUserID, u.ID)
+	}), nil
+}
+
+func (r *UserRepository) AllPage(ctx context.Context, currentUserID int, p *store.Pagination) (*store.UserPage, error) {
+	afterID, err := p.AfterID()
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	users := r.sorted(func(u *model.User) bool {
+		return u.ID != currentUserID && !r.s.blocked(currentUserID, u.ID) && u.ID > afterID
+	})
+	r.s.mu.Unlock()
+
+	limit := p.PageLimit()
+	page := &store.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = store.EncodeCursor(store.IDCursor{ID: users[limit-1].ID})
+	}
+
+	return page, nil
+}
+
+func (r *UserRepository) FindByFilters(ctx context.Context, currentUserID int, f *store.UserFilter) (*store.UserPage, error) {
+	return r.findByFilters(currentUserID, f, func(u *model.User) bool {
+		return true
+	})
+}
+
+func (r *UserRepository) Feed(ctx context.Context, currentUserID int, f *store.UserFilter) (*store.UserPage, error) {
+	return r.findByFilters(currentUserID, f, func(u *model.User) bool {
+		for _, l := range r.s.likes {
+			if l.UserID == currentUserID && l.LikedUser == u.ID {
+				return false
+			}
+		}
+		for _, p := range r.s.passes {
+			if p.UserID == currentUserID && p.PassedUser == u.ID {
+				return false
+			}
+		}
+		for _, m := range r.s.matches {
+			if (m.User1 == currentUserID && m.User2 == u.ID) || (m.User2 == currentUserID && m.User1 == u.ID) {
+				return false
+			}
+		}
+		for _, m := range r.s.unmatches {
+			if (m.UserID == currentUserID && m.UnmatchedUser == u.ID) || (m.UnmatchedUser == currentUserID && m.UserID == u.ID) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// findByFilters pages through the users matching f and accepted by keep,
+// which is called with r.s.mu held.
+func (r *UserRepository) findByFilters(currentUserID int, f *store.UserFilter, keep func(u *model.User) bool) (*store.UserPage, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &store.UserCursor{}
+	if f.Cursor != "" {
+		if err := store.DecodeCursor(f.Cursor, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.MaxDistance != 0 && f.Origin == nil {
+		return nil, store.ErrNoLocation
+	}
+
+	r.s.mu.Lock()
+	users := r.sorted(func(u *model.User) bool {
+		if u.ID == currentUserID || r.s.blocked(currentUserID, u.ID) {
+			return false
+		}
+		if f.MinAge != 0 && u.Age < f.MinAge {
+			return false
+		}
+		if f.MaxAge != 0 && u.Age > f.MaxAge {
+			return false
+		}
+		if f.Gender != "" && u.Gender != f.Gender {
+			return false
+		}
+		if len(f.Genders) != 0 && !contains(f.Genders, u.Gender) {
+			return false
+		}
+		if len(f.Cities) != 0 && !contains(f.Cities, u.City) {
+			return false
+		}
+		if f.HasAbout && u.About == "" {
+			return false
+		}
+		if f.Mutual && !r.accepts(u.ID, currentUserID) {
+			return false
+		}
+		if f.MaxDistance != 0 && (u.Location == nil || f.Origin.Snap().DistanceTo(u.Location.Snap()) > float64(f.MaxDistance)) {
+			return false
+		}
+		return keep(u)
+	})
+	r.s.mu.Unlock()
+
+	switch f.Sort {
+	case store.SortByAgeAsc:
+		sort.SliceStable(users, func(i, j int) bool {
+			return users[i].Age < users[j].Age
+		})
+	case store.SortByAgeDesc:
+		sort.SliceStable(users, func(i, j int) bool {
+			return users[i].Age > users[j].Age
+		})
+	case store.SortByLastSeen:
+		sort.SliceStable(users, func(i, j int) bool {
+			return users[i].LastSeenAt.After(users[j].LastSeenAt)
+		})
+	}
+
+	if f.Cursor != "" {
+		rest := make([]*model.User, 0)
+		for _, u := range users {
+			if afterCursor(f.Sort, c, u) {
+				rest = append(rest, u)
+			}
+		}
+		users = rest
+	}
+
+	limit := f.PageLimit()
+	page := &store.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = store.NextUserCursor(f.Sort, users[limit-1])
+	}
+
+	return page, nil
+}
+
+// accepts reports whether the user with id fits the preferences of the user
+// with ownerID. Users without preferences accept everyone. The caller must
+// hold r.s.mu.
+func (r *UserRepository) accepts(ownerID, id int) bool {
+	p, ok := r.s.preferences[ownerID]
+	u, found := r.s.users[id]
+	return !ok || !found || p.Accepts(u)
+}
+
+func afterCursor(sort string, c *store.UserCursor, u *model.User) bool {
+	switch sort {
+	case store.SortByAgeAsc:
+		return u.Age > c.Age || (u.Age == c.Age && u.ID > c.ID)
+	case store.SortByAgeDesc:
+		return u.Age < c.Age || (u.Age == c.Age && u.ID > c.ID)
+	case store.SortByLastSeen:
+		var seen time.Time
+		if c.LastSeen != nil {
+			seen = *c.LastSeen
+		}
+		return u.LastSeenAt.Before(seen) || (u.LastSeenAt.Equal(seen) && u.ID > c.ID)
+	default:
+		return u.ID > c.ID
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.s.users), nil
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []int) ([]*model.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := r.s.users[id]; ok {
+			users = append(users, copyUser(u))
+		}
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*model.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Login == login {
+			return copyUser(u), nil
+		}
+	}
+
+	return nil, store.ErrNotFound
+}
+
+func (r *UserRepository) Update(ctx context.Context, u *model.User) error {
+	if err := u.ValidateUpdate(); err != nil {
+		return err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[u.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	if err := r.checkUnique(u); err != nil {
+		return err
+	}
+
+	if u.Timezone == "" {
+		u.Timezone = "UTC"
+	}
+
+	updated := copyUser(u)
+	updated.EncryptedPassword = stored.EncryptedPassword
+	updated.Location = stored.Location
+	updated.LastSeenAt = stored.LastSeenAt
+	r.s.users[u.ID] = updated
+
+	return nil
+}
+
+func (r *UserRepository) UpdateLastSeen(ctx context.Context, id int, t time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	updated := copyUser(stored)
+	updated.LastSeenAt = t
+	r.s.users[id] = updated
+
+	return nil
+}
+
+func (r *UserRepository) UpdateLocation(ctx context.Context, id int, l *model.Location) error {
+	if l != nil {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	updated := copyUser(stored)
+	updated.Location = nil
+	if l != nil {
+		location := *l
+		updated.Location = &location
+	}
+	r.s.users[id] = updated
+
+	return nil
+}
+
+func (r *UserRepository) DeleteById(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, l := range r.s.likes {
+		if l.UserID == id || l.LikedUser == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, m := range r.s.matches {
+		if m.User1 == id || m.User2 == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, p := range r.s.passes {
+		if p.UserID == id || p.PassedUser == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, u := range r.s.unmatches {
+		if u.UserID == id || u.UnmatchedUser == id {
+			return store.ErrStillReferenced
+		}
+	}
+	if _, ok := r.s.preferences[id]; ok {
+		return store.ErrStillReferenced
+	}
+	for _, c := range r.s.counters {
+		if c.UserID == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, b := range r.s.blocks {
+		if b.UserID == id || b.BlockedUser == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, rep := range r.s.reports {
+		if rep.UserID == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, m := range r.s.messages {
+		if m.Sender == id || m.Recipient == id {
+			return store.ErrStillReferenced
+		}
+	}
+	for _, e := range r.s.events {
+		if e.UserID == id {
+			return store.ErrStillReferenced
+		}
+	}
+
+	if _, ok := r.s.users[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(r.s.users, id)
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package teststore_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kek-flip/scotch-api/internal/model"
+	"github.com/kek-flip/scotch-api/internal/store"
+	"github.com/kek-flip/scotch-api/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	s := teststore.New()
+	u := testUser(t)
+
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	assert.NotZero(t, u.ID)
+}
+
+func TestUserRepository_UniqueLoginConstraint(t *testing.T) {
+	s := teststore.New()
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.PhoneNumber = "+79001234567"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.Error(t, s.User().Create(context.Background(), u2))
+}
+
+func TestUserRepository_UniquePhoneNumberConstraint(t *testing.T) {
+	s := teststore.New()
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.Error(t, s.User().Create(context.Background(), u2))
+}
+
+func TestUserRepository_FindById(t *testing.T) {
+	s := teststore.New()
+
+	_, err := s.User().FindById(context.Background(), 1)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	original_user := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), original_user))
+
+	original_user.ClearPassword()
+
+	found_user, err := s.User().FindById(context.Background(), original_user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, original_user, found_user)
+}
+
+func TestUserRepository_FindByLogin(t *testing.T) {
+	s := teststore.New()
+
+	_, err := s.User().FindByLogin(context.Background(), "valid_login")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	original_user := testUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), original_user))
+
+	original_user.ClearPassword()
+
+	found_user, err := s.User().FindByLogin(context.Background(), original_user.Login)
+	assert.NoError(t, err)
+	assert.Equal(t, original_user, found_user)
+}
+
+func TestUserRepository_DeleteById(t *testing.T) {
+	s := teststore.New()
+	u := testUser(t)
+
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	assert.NoError(t, s.User().DeleteById(context.Background(), u.ID))
+
+	_, err := s.User().FindById(context.Background(), u.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestUserRepository_DeleteReferencedUser(t *testing.T) {
+	s := teststore.New()
+	l := testLike(t, s)
+
+	assert.NoError(t, s.Like().Create(context.Background(), l))
+	assert.Error(t, s.User().DeleteById(context.Background(), l.UserID))
+}
+
+func TestUserRepository_FindByFilters(t *testing.T) {
+	s := teststore.New()
+
+	ages := []int{30, 20, 25, 20}
+	cities := []string{"Moscow", "Kazan", "Moscow", "Sochi"}
+	users := make([]*model.User, len(ages))
+	for i := range ages {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		u.Age = ages[i]
+		u.City = cities[i]
+		if i == 3 {
+			u.About = ""
+		}
+		assert.NoError(t, s.User().Create(context.Background(), u))
+		users[i] = u
+	}
+
+	ids := func(page *store.UserPage) []int {
+		res := make([]int, 0)
+		for _, u := range page.Users {
+			res = append(res, u.ID)
+		}
+		return res
+	}
+
+	testCases := []struct {
+		name     string
+		filter   *store.UserFilter
+		expected []int
+	}{
+		{
+			name:     "no filters",
+			filter:   &store.UserFilter{},
+			expected: []int{users[1].ID, users[2].ID, users[3].ID},
+		},
+		{
+			name:     "min age only",
+			filter:   &store.UserFilter{MinAge: 21},
+			expected: []int{users[2].ID},
+		},
+		{
+			name:     "several cities",
+			filter:   &store.UserFilter{Cities: []string{"Kazan", "Sochi"}},
+			expected: []int{users[1].ID, users[3].ID},
+		},
+		{
+			name:     "has about",
+			filter:   &store.UserFilter{HasAbout: true},
+			expected: []int{users[1].ID, users[2].ID},
+		},
+		{
+			name:     "age descending",
+			filter:   &store.UserFilter{Sort: store.SortByAgeDesc},
+			expected: []int{users[2].ID, users[1].ID, users[3].ID},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := s.User().FindByFilters(context.Background(), users[0].ID, tc.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, ids(page))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		f := &store.UserFilter{Sort: store.SortByAgeAsc, Pagination: store.Pagination{Limit: 2}}
+
+		page, err := s.User().FindByFilters(context.Background(), users[2].ID, f)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{users[1].ID, users[3].ID}, ids(page))
+		assert.NotEmpty(t, page.NextCursor)
+
+		f.Cursor = page.NextCursor
+		page, err = s.User().FindByFilters(context.Background(), users[2].ID, f)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{users[0].ID}, ids(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("last seen pagination", func(t *testing.T) {
+		now := time.Now()
+		assert.NoError(t, s.User().UpdateLastSeen(context.Background(), users[1].ID, now.Add(-time.Hour)))
+		assert.NoError(t, s.User().UpdateLastSeen(context.Background(), users[3].ID, now))
+
+		f := &store.UserFilter{Sort: store.SortByLastSeen, Pagination: store.Pagination{Limit: 2}}
+
+		page, err := s.User().FindByFilters(context.Background(), users[0].ID, f)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{users[3].ID, users[1].ID}, ids(page))
+		assert.NotEmpty(t, page.NextCursor)
+
+		// Users never seen come last.
+		f.Cursor = page.NextCursor
+		page, err = s.User().FindByFilters(context.Background(), users[0].ID, f)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{users[2].ID}, ids(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := s.User().FindByFilters(context.Background(), users[0].ID, &store.UserFilter{Pagination: store.Pagination{Cursor: "!"}})
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+}
+
+func TestUserRepository_Feed(t *testing.T) {
+	s := teststore.New()
+	ctx := context.Background()
+
+	users := make([]*model.User, 3)
+	for i := range users {
+		u := testUser(t)
+		u.Login = fmt.Sprintf("login_%d", i)
+		u.PhoneNumber = fmt.Sprintf("+7999999999%d", i)
+		assert.NoError(t, s.User().Create(ctx, u))
+		users[i] = u
+	}
+
+	feed := func(userID int) []int {
+		page, err := s.User().Feed(ctx, userID, &store.UserFilter{})
+		assert.NoError(t, err)
+
+		ids := make([]int, 0)
+		for _, u := range page.Users {
+			ids = append(ids, u.ID)
+		}
+		return ids
+	}
+
+	assert.NoError(t, s.Pass().Create(ctx, &model.Pass{UserID: users[0].ID, PassedUser: users[1].ID}))
+	assert.Equal(t, []int{users[2].ID}, feed(users[0].ID))
+
+	// An unmatch keeps both users out of each other's feed, even though the
+	// like of the other one is still there.
+	assert.NoError(t, s.Like().Create(ctx, &model.Like{UserID: users[2].ID, LikedUser: users[1].ID}))
+	assert.NoError(t, s.Unmatch().Create(ctx, &model.Unmatch{UserID: users[1].ID, UnmatchedUser: users[2].ID}))
+	assert.Equal(t, []int{users[0].ID}, feed(users[1].ID))
+	assert.Equal(t, []int{users[0].ID}, feed(users[2].ID))
+}
+
+func TestUserRepository_FindByIDs(t *testing.T) {
+	s := teststore.New()
+
+	u1 := testUser(t)
+	u2 := testUser(t)
+	u2.Login = "other_valid_login"
+	u2.PhoneNumber = "+79001234567"
+	assert.NoError(t, s.User().Create(context.Background(), u1))
+	assert.NoError(t, s.User().Create(context.Background(), u2))
+
+	users, err := s.User().FindByIDs(context.Background(), []int{u2.ID, 100, u1.ID})
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, u2.ID, users[0].ID)
+	assert.Equal(t, u1.ID, users[1].ID)
+
+	users, err = s.User().FindByIDs(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
@@ -0,0 +1,3 @@
+DROP INDEX users_city_idx;
+DROP INDEX users_age_idx;
+DROP INDEX users_gender_age_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX users_gender_age_idx ON users (gender, age, user_id);
+CREATE INDEX users_age_idx ON users (age, user_id);
+CREATE INDEX users_city_idx ON users (city);
@@ -0,0 +1 @@
+ALTER TABLE matches DROP CONSTRAINT ordered_users;
@@ -0,0 +1,9 @@
+DELETE FROM matches m
+    USING matches d
+    WHERE LEAST(m.user_1, m.user_2) = LEAST(d.user_1, d.user_2)
+        AND GREATEST(m.user_1, m.user_2) = GREATEST(d.user_1, d.user_2)
+        AND m.match_id > d.match_id;
+
+UPDATE matches SET user_1 = user_2, user_2 = user_1 WHERE user_1 > user_2;
+
+ALTER TABLE matches ADD CONSTRAINT ordered_users CHECK(user_1 < user_2);
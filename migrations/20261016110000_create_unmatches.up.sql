@@ -0,0 +1,10 @@
+CREATE TABLE unmatches (
+    unmatch_id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    unmatched_user INTEGER REFERENCES users(user_id) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX unmatches_user_id_idx ON unmatches (user_id);
+CREATE INDEX unmatches_unmatched_user_idx ON unmatches (unmatched_user);
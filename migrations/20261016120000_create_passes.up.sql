@@ -0,0 +1,8 @@
+CREATE TABLE passes (
+    pass_id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    passed_user INTEGER REFERENCES users(user_id) NOT NULL CONSTRAINT diff_users CHECK(passed_user != user_id),
+    CONSTRAINT passes_unique_pair UNIQUE(user_id, passed_user)
+);
+
+CREATE INDEX passes_passed_user_idx ON passes (passed_user);
@@ -0,0 +1,9 @@
+CREATE TABLE preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(user_id),
+    genders VARCHAR(6)[] NOT NULL DEFAULT '{}',
+    min_age INTEGER NOT NULL DEFAULT 0 CHECK(min_age = 0 OR min_age BETWEEN 18 AND 99),
+    max_age INTEGER NOT NULL DEFAULT 0 CHECK(max_age = 0 OR max_age BETWEEN 18 AND 99),
+    cities VARCHAR(25)[] NOT NULL DEFAULT '{}',
+    max_distance INTEGER NOT NULL DEFAULT 0 CHECK(max_distance >= 0),
+    mutual BOOLEAN NOT NULL DEFAULT false
+);
@@ -0,0 +1,6 @@
+DROP INDEX users_location_idx;
+
+ALTER TABLE users
+    DROP CONSTRAINT complete_location,
+    DROP latitude,
+    DROP longitude;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD latitude DOUBLE PRECISION CHECK(latitude BETWEEN -90 AND 90),
+    ADD longitude DOUBLE PRECISION CHECK(longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT complete_location CHECK((latitude IS NULL) = (longitude IS NULL));
+
+CREATE INDEX users_location_idx ON users (latitude, longitude) WHERE latitude IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP interests,
+    DROP last_seen_at;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD interests VARCHAR(25)[] NOT NULL DEFAULT '{}',
+    ADD last_seen_at TIMESTAMPTZ;
@@ -0,0 +1,8 @@
+DROP INDEX likes_user_id_created_at_idx;
+
+ALTER TABLE likes
+    DROP type,
+    DROP created_at;
+
+ALTER TABLE users
+    DROP timezone;
@@ -0,0 +1,8 @@
+ALTER TABLE users
+    ADD timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE likes
+    ADD type VARCHAR(7) NOT NULL DEFAULT 'regular' CONSTRAINT like_type CHECK(type IN ('regular', 'super')),
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX likes_user_id_created_at_idx ON likes (user_id, created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE counters (
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL CHECK(window_end > window_start),
+    count INTEGER NOT NULL DEFAULT 0 CHECK(count >= 0),
+    PRIMARY KEY (user_id, name, window_start)
+);
@@ -0,0 +1,7 @@
+DROP INDEX passes_user_id_created_at_idx;
+
+ALTER TABLE passes
+    DROP created_at;
+
+ALTER TABLE matches
+    DROP created_at;
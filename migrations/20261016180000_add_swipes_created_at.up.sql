@@ -0,0 +1,7 @@
+ALTER TABLE matches
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE passes
+    ADD created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX passes_user_id_created_at_idx ON passes (user_id, created_at);
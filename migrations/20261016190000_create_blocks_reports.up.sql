@@ -0,0 +1,23 @@
+CREATE TABLE blocks (
+    block_id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    blocked_user INTEGER REFERENCES users(user_id) NOT NULL CONSTRAINT diff_users CHECK(blocked_user != user_id),
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT blocks_unique_pair UNIQUE(user_id, blocked_user)
+);
+
+CREATE INDEX blocks_blocked_user_idx ON blocks (blocked_user);
+
+CREATE TABLE reports (
+    report_id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    reported_user INTEGER REFERENCES users(user_id) NOT NULL CONSTRAINT diff_users CHECK(reported_user != user_id),
+    reason VARCHAR(16) NOT NULL,
+    comment VARCHAR(1000) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX reports_reported_user_idx ON reports (reported_user);
+CREATE INDEX reports_pending_idx ON reports (created_at) WHERE status = 'pending';
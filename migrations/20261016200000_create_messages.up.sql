@@ -0,0 +1,16 @@
+-- match_id does not reference matches: the thread outlives its match and is
+-- locked once the match is gone.
+CREATE TABLE messages (
+    message_id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL,
+    sender INTEGER REFERENCES users(user_id) NOT NULL,
+    recipient INTEGER REFERENCES users(user_id) NOT NULL,
+    body VARCHAR(4000) NOT NULL,
+    edited BOOLEAN NOT NULL DEFAULT false,
+    deleted BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX messages_match_id_idx ON messages (match_id, message_id);
+CREATE INDEX messages_sender_idx ON messages (sender);
+CREATE INDEX messages_recipient_idx ON messages (recipient);
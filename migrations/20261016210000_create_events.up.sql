@@ -0,0 +1,9 @@
+CREATE TABLE events (
+    event_id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(user_id) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX events_user_id_idx ON events (user_id, event_id);
@@ -0,0 +1,7 @@
+CREATE TABLE pubsub_payloads (
+    payload_id BIGSERIAL PRIMARY KEY,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX pubsub_payloads_created_at_idx ON pubsub_payloads (created_at);
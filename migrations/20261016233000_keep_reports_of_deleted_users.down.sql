@@ -0,0 +1,2 @@
+DELETE FROM reports WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.user_id = reports.reported_user);
+ALTER TABLE reports ADD CONSTRAINT reports_reported_user_fkey FOREIGN KEY (reported_user) REFERENCES users(user_id);
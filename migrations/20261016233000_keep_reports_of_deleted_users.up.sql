@@ -0,0 +1 @@
+ALTER TABLE reports DROP CONSTRAINT reports_reported_user_fkey;